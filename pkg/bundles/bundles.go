@@ -15,7 +15,7 @@ import (
 type AppNameOverrideType int
 
 const (
-	// AppNameOverrideAuto automatically detects the naming convention based on the bundle app name
+	// AppNameOverrideAuto uses the naming convention of the bundle from the registry, or detects it from the bundle's values
 	AppNameOverrideAuto AppNameOverrideType = iota
 	// AppNameOverrideCamelCase converts the app name to camelCase (e.g., "my-app" -> "myApp")
 	AppNameOverrideCamelCase
@@ -28,11 +28,27 @@ const (
 // OverrideChildApp takes two apps, a bundle app and a child app, and attempts to correctly set the values of the bundle app
 // to have it install the desired version of the child app.
 // The overrideType specifies the naming convention for the child app.
-// If set to AppNameOverrideAuto, it will attempt to auto-detect based on the bundle app name.
+// If set to AppNameOverrideAuto, the convention of the bundle is taken from the registry (see Register), or detected
+// from the bundle's published values.yaml if it isn't registered.
 func OverrideChildApp(bundleApp *application.Application, childApp *application.Application, overrideType AppNameOverrideType) (*application.Application, error) {
-	appName := childApp.AppName
+	bundle, ok := Lookup(bundleApp.AppName)
+	if overrideType != AppNameOverrideAuto {
+		// An explicit naming convention takes precedence, the registry still provides where the child Apps are set
+		bundle.NameConvention = overrideType
+	} else if !ok || bundle.NameConvention == AppNameOverrideAuto {
+		var registered *Bundle
+		if ok {
+			registered = &bundle
+		}
+		var err error
+		bundle, err = detectAndRegisterBundle(bundleApp.AppName, bundleApp.Version, childApp.AppName, registered)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	switch overrideType {
+	appName := childApp.AppName
+	switch bundle.NameConvention {
 	case AppNameOverrideNone:
 		// No override values, return bundle app unchanged
 		return bundleApp, nil
@@ -40,29 +56,24 @@ func OverrideChildApp(bundleApp *application.Application, childApp *application.
 		appName = toCamelCase(appName)
 	case AppNameOverrideHyphen:
 		// Keep as-is (hyphenated)
-	case AppNameOverrideAuto:
-		fallthrough
 	default:
-		// Auto-detect based on bundle app name
-		if isCamelCaseName(bundleApp.AppName) {
-			appName = toCamelCase(appName)
-		} else if !isHyphenName(bundleApp.AppName) {
-			return nil, fmt.Errorf("provided bundle is unsupported, child version override format is unknown")
-		}
+		return nil, fmt.Errorf("provided bundle is unsupported, child version override format is unknown")
 	}
 
-	overrideValues := bundleValues{
-		Apps: map[string]appValues{
-			appName: {
-				Enabled:   true,
-				Catalog:   childApp.Catalog,
-				Version:   childApp.Version,
-				AppName:   childApp.AppName,
-				ChartName: childApp.AppName,
-				Namespace: childApp.InstallNamespace,
-			},
+	var overrideValues any = map[string]any{
+		appName: map[string]any{
+			"enabled":             true,
+			"catalog":             childApp.Catalog,
+			"version":             childApp.Version,
+			"appName":             childApp.AppName,
+			"chartName":           childApp.AppName,
+			bundle.NamespaceField: childApp.InstallNamespace,
 		},
 	}
+	keys := strings.Split(bundle.ValuesKeyPath, ".")
+	for i := len(keys) - 1; i >= 0; i-- {
+		overrideValues = map[string]any{keys[i]: overrideValues}
+	}
 
 	valuesLayer, err := yaml.Marshal(overrideValues)
 	if err != nil {
@@ -84,14 +95,3 @@ func toCamelCase(appName string) string {
 	appName = strings.ReplaceAll(appName, " ", "")
 	return strings.ToLower(appName[:1]) + appName[1:]
 }
-
-func isCamelCaseName(appName string) bool {
-	return strings.EqualFold(appName, "security-bundle") ||
-		strings.EqualFold(appName, "observability-bundle") ||
-		strings.EqualFold(appName, "gateway-api-bundle")
-}
-
-func isHyphenName(appName string) bool {
-	return strings.EqualFold(appName, "service-mesh-bundle") ||
-		strings.EqualFold(appName, "auth-bundle")
-}
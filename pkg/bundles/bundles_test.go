@@ -1,6 +1,7 @@
 package bundles
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/giantswarm/clustertest/v5/pkg/application"
@@ -14,6 +15,8 @@ func TestOverrideChildApp(t *testing.T) {
 		childApp     *application.Application
 		expectedName string
 		expectsError bool
+		// publishedValues is the values.yaml of bundles that aren't registered
+		publishedValues string
 	}{
 		{
 			name:         "security-bundle - existing child app",
@@ -69,10 +72,26 @@ func TestOverrideChildApp(t *testing.T) {
 			childApp:     application.New("test-new-app", "new-app").WithCatalog("test-catalog").WithVersion("1.2.3"),
 			expectsError: true,
 		},
+		{
+			name:            "unregistered bundle - detected from values",
+			bundleApp:       application.New("test-detected-bundle", "detected-bundle"),
+			childApp:        application.New("test-new-app", "new-app").WithCatalog("test-catalog").WithVersion("1.2.3"),
+			publishedValues: "apps:\n  certManager:\n    appName: cert-manager\n    enabled: true\n",
+			expectedName:    "newApp",
+		},
 	}
 
+	restoreRegistryAfter(t)
+
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
+			fetchBundleValues = func(string, string) (string, error) {
+				if tc.publishedValues == "" {
+					return "", errors.New("not found")
+				}
+				return tc.publishedValues, nil
+			}
+
 			result, err := OverrideChildApp(tc.bundleApp, tc.childApp, AppNameOverrideAuto)
 
 			if err != nil && !tc.expectsError {
@@ -106,3 +125,193 @@ func TestOverrideChildApp(t *testing.T) {
 		})
 	}
 }
+
+func TestOverrideChildAppRegisteredBundle(t *testing.T) {
+	restoreRegistryAfter(t)
+
+	Register("platform-bundle", Bundle{
+		NameConvention: AppNameOverrideCamelCase,
+		ValuesKeyPath:  "bundle.apps",
+		NamespaceField: "targetNamespace",
+	})
+
+	bundleApp := application.New("test-platform-bundle", "platform-bundle")
+	childApp := application.New("test-new-app", "new-app").WithCatalog("test-catalog").WithVersion("1.2.3").WithInstallNamespace("new-app")
+	result, err := OverrideChildApp(bundleApp, childApp, AppNameOverrideAuto)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := map[string]map[string]map[string]map[string]any{}
+	_ = yaml.Unmarshal([]byte(result.Values), &values)
+
+	childAppValues, ok := values["bundle"]["apps"]["newApp"]
+	if !ok {
+		t.Fatalf("Didn't find expected child app values in %s", result.Values)
+	}
+	if childAppValues["version"] != childApp.Version {
+		t.Fatalf("Version didn't match expected. Expected '%s', Actual: '%v'", childApp.Version, childAppValues["version"])
+	}
+	if childAppValues["targetNamespace"] != childApp.InstallNamespace {
+		t.Fatalf("Namespace didn't match expected. Expected '%s', Actual: '%v'", childApp.InstallNamespace, childAppValues["targetNamespace"])
+	}
+}
+
+func TestOverrideChildAppRegisteredBundleDetectedNaming(t *testing.T) {
+	restoreRegistryAfter(t)
+
+	Register("platform-bundle", Bundle{ValuesKeyPath: "bundle.apps", NamespaceField: "targetNamespace"})
+	fetchBundleValues = func(string, string) (string, error) {
+		return "bundle:\n  apps:\n    cert-manager:\n      enabled: true\n", nil
+	}
+
+	bundleApp := application.New("test-platform-bundle", "platform-bundle")
+	childApp := application.New("test-new-app", "new-app").WithCatalog("test-catalog").WithVersion("1.2.3").WithInstallNamespace("new-app")
+	result, err := OverrideChildApp(bundleApp, childApp, AppNameOverrideAuto)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := map[string]map[string]map[string]map[string]any{}
+	_ = yaml.Unmarshal([]byte(result.Values), &values)
+
+	childAppValues, ok := values["bundle"]["apps"]["new-app"]
+	if !ok {
+		t.Fatalf("Didn't find expected child app values in %s", result.Values)
+	}
+	if childAppValues["targetNamespace"] != childApp.InstallNamespace {
+		t.Fatalf("Namespace didn't match expected. Expected '%s', Actual: '%v'", childApp.InstallNamespace, childAppValues["targetNamespace"])
+	}
+}
+
+func TestParseAppNameOverrideType(t *testing.T) {
+	tests := []struct {
+		convention   string
+		expected     AppNameOverrideType
+		expectsError bool
+	}{
+		{convention: "", expected: AppNameOverrideAuto},
+		{convention: "auto", expected: AppNameOverrideAuto},
+		{convention: "camelCase", expected: AppNameOverrideCamelCase},
+		{convention: "hyphen", expected: AppNameOverrideHyphen},
+		{convention: "none", expected: AppNameOverrideNone},
+		{convention: "snake_case", expectsError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.convention, func(t *testing.T) {
+			result, err := ParseAppNameOverrideType(tc.convention)
+			if (err != nil) != tc.expectsError {
+				t.Fatalf("Expected error: %t but got %v", tc.expectsError, err)
+			}
+			if !tc.expectsError && result != tc.expected {
+				t.Fatalf("Expected %s but got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestDetectBundle(t *testing.T) {
+	tests := []struct {
+		name         string
+		values       string
+		childAppName string
+		expected     Bundle
+		expectsError bool
+	}{
+		{
+			name:         "camelCase child app key",
+			values:       "apps:\n  kyvernoPolicies:\n    appName: kyverno-policies\n    namespace: kyverno\n",
+			childAppName: "kyverno-policies",
+			expected:     Bundle{NameConvention: AppNameOverrideCamelCase, ValuesKeyPath: "apps", NamespaceField: "namespace"},
+		},
+		{
+			name:         "hyphen child app key",
+			values:       "apps:\n  linkerd-control-plane:\n    appName: linkerd-control-plane\n",
+			childAppName: "linkerd-control-plane",
+			expected:     Bundle{NameConvention: AppNameOverrideHyphen, ValuesKeyPath: "apps", NamespaceField: "namespace"},
+		},
+		{
+			name:         "new child app, from other keys",
+			values:       "apps:\n  ingress-nginx:\n    appName: ingress-nginx\n  dex:\n    appName: dex-app\n",
+			childAppName: "new-app",
+			expected:     Bundle{NameConvention: AppNameOverrideHyphen, ValuesKeyPath: "apps", NamespaceField: "namespace"},
+		},
+		{
+			name:         "custom key and namespace field",
+			values:       "global:\n  enabled: true\nchildApps:\n  certManager:\n    chartName: cert-manager\n    targetNamespace: cert-manager\n",
+			childAppName: "new-app",
+			expected:     Bundle{NameConvention: AppNameOverrideCamelCase, ValuesKeyPath: "childApps", NamespaceField: "targetNamespace"},
+		},
+		{
+			name:         "single word child apps",
+			values:       "apps:\n  dex:\n    appName: dex\n",
+			childAppName: "athena",
+			expected:     Bundle{NameConvention: AppNameOverrideHyphen, ValuesKeyPath: "apps", NamespaceField: "namespace"},
+		},
+		{
+			name:         "ambiguous",
+			values:       "apps:\n  dex:\n    appName: dex\n",
+			childAppName: "new-app",
+			expectsError: true,
+		},
+		{
+			name:         "no child apps",
+			values:       "replicas: 1\n",
+			childAppName: "new-app",
+			expectsError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bundle, err := DetectBundle(tc.values, tc.childAppName)
+			if tc.expectsError {
+				if err == nil {
+					t.Fatalf("Expected an error but got %+v", bundle)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bundle != tc.expected {
+				t.Fatalf("Expected %+v but got %+v", tc.expected, bundle)
+			}
+		})
+	}
+}
+
+type bundleValues struct {
+	Apps map[string]appValues `yaml:"apps"`
+}
+
+type appValues struct {
+	Enabled   bool   `yaml:"enabled"`
+	Catalog   string `yaml:"catalog"`
+	Version   string `yaml:"version"`
+	AppName   string `yaml:"appName"`
+	ChartName string `yaml:"chartName"`
+	Namespace string `yaml:"namespace"`
+}
+
+// restoreRegistryAfter restores the bundle registry and values fetcher once the test completes, so bundles
+// registered or detected by it don't leak into other tests
+func restoreRegistryAfter(t *testing.T) {
+	t.Helper()
+
+	registryMu.RLock()
+	saved := make(map[string]Bundle, len(registry))
+	for name, bundle := range registry {
+		saved[name] = bundle
+	}
+	registryMu.RUnlock()
+	savedFetch := fetchBundleValues
+
+	t.Cleanup(func() {
+		registryMu.Lock()
+		registry = saved
+		registryMu.Unlock()
+		fetchBundleValues = savedFetch
+	})
+}
@@ -0,0 +1,299 @@
+package bundles
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultValuesKeyPath is the values path of the child Apps of most bundles.
+	DefaultValuesKeyPath = "apps"
+	// DefaultNamespaceField is the field of a child App's values setting the namespace it's installed into.
+	DefaultNamespaceField = "namespace"
+
+	// bundleValuesURL is the URL of the published values.yaml of a bundle chart, by repo, ref and chart name
+	bundleValuesURL = "https://raw.githubusercontent.com/giantswarm/%s/%s/helm/%s/values.yaml"
+)
+
+// Bundle describes how a bundle App configures its child Apps in its values.
+type Bundle struct {
+	// NameConvention is how the child App names are formatted as keys of the bundle values, either
+	// AppNameOverrideCamelCase or AppNameOverrideHyphen. If AppNameOverrideAuto it's detected from the
+	// published values of the bundle.
+	NameConvention AppNameOverrideType
+	// ValuesKeyPath is the dot-separated path of the child Apps within the bundle values. Defaults to `apps`.
+	ValuesKeyPath string
+	// NamespaceField is the field of a child App's values setting its namespace. Defaults to `namespace`.
+	NamespaceField string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Bundle{
+		"security-bundle":      {NameConvention: AppNameOverrideCamelCase},
+		"observability-bundle": {NameConvention: AppNameOverrideCamelCase},
+		"gateway-api-bundle":   {NameConvention: AppNameOverrideCamelCase},
+		"service-mesh-bundle":  {NameConvention: AppNameOverrideHyphen},
+		"auth-bundle":          {NameConvention: AppNameOverrideHyphen},
+	}
+
+	// fetchBundleValues returns the published values.yaml of the bundle at the given version
+	fetchBundleValues = fetchPublishedValues
+)
+
+// Register adds the bundle to the registry, replacing any existing entry of the same name. Empty fields are
+// defaulted. Bundles not registered have their naming convention detected from their published values.yaml.
+func Register(bundleName string, bundle Bundle) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(bundleName)] = bundle
+}
+
+// Lookup returns the registered bundle with the given name, with empty fields defaulted.
+func Lookup(bundleName string) (Bundle, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	bundle, ok := registry[strings.ToLower(bundleName)]
+	return bundle.withDefaults(), ok
+}
+
+// ParseAppNameOverrideType parses a naming convention as set in config.yaml: `camelCase`, `hyphen` or `none`.
+// An empty convention, or `auto`, is AppNameOverrideAuto to have it detected.
+func ParseAppNameOverrideType(convention string) (AppNameOverrideType, error) {
+	switch strings.ToLower(convention) {
+	case "", "auto":
+		return AppNameOverrideAuto, nil
+	case "camelcase":
+		return AppNameOverrideCamelCase, nil
+	case "hyphen":
+		return AppNameOverrideHyphen, nil
+	case "none":
+		return AppNameOverrideNone, nil
+	default:
+		return AppNameOverrideAuto, fmt.Errorf("unknown bundle naming convention '%s', must be camelCase, hyphen, none or auto", convention)
+	}
+}
+
+// DetectBundle detects how the bundle configures its child Apps from its values.yaml. The child Apps are looked
+// for at the `apps` key, or any other top-level map of Apps, and their naming convention is taken from the key of
+// the App being tested if already part of the bundle, otherwise from the keys of the other child Apps.
+func DetectBundle(valuesYAML string, childAppName string) (Bundle, error) {
+	values := map[string]any{}
+	if err := sigsyaml.Unmarshal([]byte(valuesYAML), &values); err != nil {
+		return Bundle{}, fmt.Errorf("parsing bundle values: %w", err)
+	}
+
+	keyPath, apps := findChildApps(values)
+	if apps == nil {
+		return Bundle{}, fmt.Errorf("no child Apps found in the bundle values")
+	}
+	bundle := Bundle{ValuesKeyPath: keyPath, NamespaceField: DefaultNamespaceField}
+
+	// Some bundles set the namespace of their child Apps via `targetNamespace` instead
+	hasNamespace, hasTargetNamespace := false, false
+	for _, app := range apps {
+		if appMap, ok := app.(map[string]any); ok {
+			_, ok := appMap[DefaultNamespaceField]
+			hasNamespace = hasNamespace || ok
+			_, ok = appMap["targetNamespace"]
+			hasTargetNamespace = hasTargetNamespace || ok
+		}
+	}
+	if hasTargetNamespace && !hasNamespace {
+		bundle.NamespaceField = "targetNamespace"
+	}
+
+	nameConvention, err := detectNameConvention(apps, childAppName)
+	if err != nil {
+		return Bundle{}, err
+	}
+	bundle.NameConvention = nameConvention
+	return bundle, nil
+}
+
+// detectNameConvention detects the naming convention of the child Apps of a bundle from the keys of its child Apps,
+// the key of the App being tested if already part of the bundle, otherwise from the keys of the other child Apps
+func detectNameConvention(apps map[string]any, childAppName string) (AppNameOverrideType, error) {
+	keys := []string{}
+	hasHyphenKeys, hasCamelCaseKeys := false, false
+	for key := range apps {
+		keys = append(keys, key)
+		hasHyphenKeys = hasHyphenKeys || strings.Contains(key, "-")
+		hasCamelCaseKeys = hasCamelCaseKeys || strings.IndexFunc(key, unicode.IsUpper) > 0
+	}
+	sort.Strings(keys)
+
+	_, hasHyphenChild := apps[childAppName]
+	_, hasCamelCaseChild := apps[toCamelCase(childAppName)]
+	isMultiWord := strings.Contains(childAppName, "-")
+
+	switch {
+	case isMultiWord && hasHyphenChild:
+		return AppNameOverrideHyphen, nil
+	case isMultiWord && hasCamelCaseChild:
+		return AppNameOverrideCamelCase, nil
+	case hasHyphenKeys && !hasCamelCaseKeys:
+		return AppNameOverrideHyphen, nil
+	case hasCamelCaseKeys && !hasHyphenKeys:
+		return AppNameOverrideCamelCase, nil
+	case !isMultiWord:
+		// A single word child App name is the same in both conventions
+		return AppNameOverrideHyphen, nil
+	default:
+		return AppNameOverrideAuto, fmt.Errorf("unable to detect the naming convention of the child Apps (%s)", strings.Join(keys, ", "))
+	}
+}
+
+// detectConfiguredBundle detects the naming convention of a bundle registered without one, from the child Apps
+// found at its configured values path
+func detectConfiguredBundle(valuesYAML string, bundle Bundle, childAppName string) (Bundle, error) {
+	values := map[string]any{}
+	if err := sigsyaml.Unmarshal([]byte(valuesYAML), &values); err != nil {
+		return Bundle{}, fmt.Errorf("parsing bundle values: %w", err)
+	}
+
+	var apps any = values
+	for _, key := range strings.Split(bundle.ValuesKeyPath, ".") {
+		current, _ := apps.(map[string]any)
+		apps = current[key]
+	}
+	appsMap, ok := apps.(map[string]any)
+	if !ok {
+		return Bundle{}, fmt.Errorf("no child Apps found at '%s' in the bundle values", bundle.ValuesKeyPath)
+	}
+
+	nameConvention, err := detectNameConvention(appsMap, childAppName)
+	if err != nil {
+		return Bundle{}, err
+	}
+	bundle.NameConvention = nameConvention
+	return bundle, nil
+}
+
+// detectAndRegisterBundle detects how the bundle configures its child Apps from its published values.yaml and
+// registers it so it's only fetched once. If the bundle is registered without a naming convention only the
+// naming convention is detected, from the child Apps at its registered values path.
+func detectAndRegisterBundle(bundleName, bundleVersion, childAppName string, registered *Bundle) (Bundle, error) {
+	valuesYAML, err := fetchBundleValues(bundleName, bundleVersion)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("the values of the bundle can't be fetched to detect the child version override format: %w", err)
+	}
+
+	var bundle Bundle
+	if registered != nil {
+		bundle, err = detectConfiguredBundle(valuesYAML, *registered, childAppName)
+	} else {
+		bundle, err = DetectBundle(valuesYAML, childAppName)
+	}
+	if err != nil {
+		return Bundle{}, fmt.Errorf("the child version override format of the bundle can't be detected: %w", err)
+	}
+
+	logger.Log("Detected bundle '%s' child Apps at '%s' (naming convention: %s, namespace field: %s)", bundleName, bundle.ValuesKeyPath, bundle.NameConvention, bundle.NamespaceField)
+	Register(bundleName, bundle)
+	return bundle, nil
+}
+
+// findChildApps returns the path and content of the map of child Apps within the bundle values
+func findChildApps(values map[string]any) (string, map[string]any) {
+	if apps, ok := values[DefaultValuesKeyPath].(map[string]any); ok {
+		return DefaultValuesKeyPath, apps
+	}
+
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		candidate, ok := values[key].(map[string]any)
+		if !ok || len(candidate) == 0 {
+			continue
+		}
+		isApps := true
+		for _, app := range candidate {
+			appMap, ok := app.(map[string]any)
+			if !ok {
+				isApps = false
+				break
+			}
+			_, hasAppName := appMap["appName"]
+			_, hasChartName := appMap["chartName"]
+			if !hasAppName && !hasChartName {
+				isApps = false
+				break
+			}
+		}
+		if isApps {
+			return key, candidate
+		}
+	}
+	return "", nil
+}
+
+// fetchPublishedValues fetches the values.yaml of the bundle chart from its GitHub repo at the tag of the given
+// version, or the default branch if no version is given. If `GITHUB_TOKEN` is set it is used to authenticate the request.
+func fetchPublishedValues(bundleName, bundleVersion string) (string, error) {
+	ref := "HEAD"
+	if bundleVersion != "" {
+		ref = "v" + strings.TrimPrefix(bundleVersion, "v")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(bundleValuesURL, bundleName, ref, bundleName), nil)
+	if err != nil {
+		return "", err
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching values of %s: %w", bundleName, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching values of %s (%s): unexpected status %s", bundleName, ref, resp.Status)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading values of %s: %w", bundleName, err)
+	}
+	return string(content), nil
+}
+
+func (b Bundle) withDefaults() Bundle {
+	if b.ValuesKeyPath == "" {
+		b.ValuesKeyPath = DefaultValuesKeyPath
+	}
+	if b.NamespaceField == "" {
+		b.NamespaceField = DefaultNamespaceField
+	}
+	return b
+}
+
+// String returns the naming convention as set in config.yaml
+func (t AppNameOverrideType) String() string {
+	switch t {
+	case AppNameOverrideCamelCase:
+		return "camelCase"
+	case AppNameOverrideHyphen:
+		return "hyphen"
+	case AppNameOverrideNone:
+		return "none"
+	default:
+		return "auto"
+	}
+}
@@ -15,26 +15,48 @@ import (
 )
 
 // InstallApp installs the given App then waits for it to be marked as installed.
-// Timeout can be controlled via the provided context
+// Timeout can be controlled via the provided context. The wait is also aborted if the suite context is cancelled.
+// The wait fails immediately with an *AppFailedError if the App or its Chart CR reports a terminal failure.
 func InstallApp(ctx context.Context, app *application.Application) {
 	GinkgoHelper()
 
+	ctx, cancel := withSuiteContext(ctx)
+	defer cancel()
+
 	builtApp, _, err := app.Build()
 	Expect(err).NotTo(HaveOccurred())
 	version := builtApp.Spec.Version
 
 	logger.Log("Installing App %s as %s (version: %s)", app.AppName, app.InstallName, version)
 
-	err = state.GetFramework().MC().DeployApp(state.GetContext(), *app)
+	previousStatus := getAppReleaseStatus(ctx, app)
+
+	err = state.GetFramework().MC().DeployApp(ctx, *app)
 	Expect(err).NotTo(HaveOccurred())
 
-	Eventually(wait.IsAppVersion(state.GetContext(), state.GetFramework().MC(), app.InstallName, app.GetNamespace(), version)).
+	Eventually(stopOnAppFailure(ctx, app, previousStatus, wait.IsAppVersion(ctx, state.GetFramework().MC(), app.InstallName, app.GetNamespace(), version))).
 		WithContext(ctx).
 		WithPolling(5 * time.Second).
 		Should(BeTrue())
 
-	Eventually(wait.IsAppDeployed(state.GetContext(), state.GetFramework().MC(), app.InstallName, app.GetNamespace())).
+	Eventually(stopOnAppFailure(ctx, app, previousStatus, wait.IsAppDeployed(ctx, state.GetFramework().MC(), app.InstallName, app.GetNamespace()))).
 		WithContext(ctx).
 		WithPolling(5 * time.Second).
 		Should(BeTrue())
 }
+
+// stopOnAppFailure wraps the wait condition so that Gomega's Eventually stops polling, with an *AppFailedError,
+// as soon as the App reports a terminal failure.
+func stopOnAppFailure(ctx context.Context, app *application.Application, previousStatus appReleaseStatus, condition wait.WaitCondition) func() (bool, error) {
+	return func() (bool, error) {
+		done, err := condition()
+		if done && err == nil {
+			return true, nil
+		}
+		if failure := checkAppFailure(ctx, app, previousStatus); failure != nil {
+			logger.Log("App '%s' has failed: %v", app.InstallName, failure)
+			return false, StopTrying("App has a terminal failure").Wrap(failure)
+		}
+		return done, err
+	}
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/clustertest/v5/pkg/application"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+)
+
+var (
+	// ErrValuesSchemaViolation is wrapped by an AppFailedError when the values don't match the chart's values schema.
+	ErrValuesSchemaViolation = errors.New("values schema violation")
+	// ErrChartNotFound is wrapped by an AppFailedError when the chart, or chart version, can't be found in the catalog.
+	ErrChartNotFound = errors.New("chart not found")
+	// ErrHelmFailure is wrapped by an AppFailedError when Helm failed to install or upgrade the release.
+	ErrHelmFailure = errors.New("helm failure")
+)
+
+// AppFailedError is returned by the App wait helpers when the App CR, or the Chart CR created for it, reports a
+// failure it won't recover from without a change. Use errors.Is with ErrValuesSchemaViolation, ErrChartNotFound
+// or ErrHelmFailure to check the type of failure.
+type AppFailedError struct {
+	// Kind is the kind of the resource reporting the failure, `App` or `Chart`.
+	Kind string
+	// Name is the name of the resource reporting the failure.
+	Name string
+	// Namespace is the namespace of the resource reporting the failure.
+	Namespace string
+	// Status is the release status reported by app-operator / chart-operator, e.g. `not-installed` or `failed`.
+	Status string
+	// Reason is the reason reported by app-operator / chart-operator.
+	Reason string
+	// Err is the type of failure.
+	Err error
+}
+
+func (e *AppFailedError) Error() string {
+	return fmt.Sprintf("%s %s/%s failed with status %s (%v): %s", e.Kind, e.Namespace, e.Name, e.Status, e.Err, e.Reason)
+}
+
+func (e *AppFailedError) Unwrap() error {
+	return e.Err
+}
+
+// GetAppFailure returns an *AppFailedError if the status of the App CR reports a terminal failure.
+// Nil is returned while the App may still be deployed.
+func GetAppFailure(app *v1alpha1.App) error {
+	if failure := appStatusFailure("App", app.Name, app.Namespace, app.Status.Release.Status, app.Status.Release.Reason); failure != nil {
+		return failure
+	}
+	return nil
+}
+
+// GetChartFailure returns an *AppFailedError if the status of the Chart CR, created by app-operator in the
+// cluster the App is installed into, reports a terminal failure. Nil is returned while the chart may still be deployed.
+func GetChartFailure(chart *v1alpha1.Chart) error {
+	if failure := appStatusFailure("Chart", chart.Name, chart.Namespace, chart.Status.Release.Status, chart.Status.Reason); failure != nil {
+		return failure
+	}
+	return nil
+}
+
+// appStatusFailure classifies a release status and reason reported by app-operator or chart-operator
+func appStatusFailure(kind, name, namespace, status, reason string) *AppFailedError {
+	newFailure := func(err error) *AppFailedError {
+		return &AppFailedError{
+			Kind:      kind,
+			Name:      name,
+			Namespace: namespace,
+			Status:    status,
+			Reason:    reason,
+			Err:       err,
+		}
+	}
+
+	lowerReason := strings.ToLower(reason)
+	switch strings.ToLower(status) {
+	case "values-schema-violation":
+		return newFailure(ErrValuesSchemaViolation)
+	case "not-installed", "validation-failed", "failed", "invalid-manifest", "already-exists":
+		if strings.Contains(lowerReason, "schema") {
+			return newFailure(ErrValuesSchemaViolation)
+		}
+		if reason == "" && strings.ToLower(status) == "not-installed" {
+			// Set without a reason before the first install attempt
+			return nil
+		}
+		return newFailure(ErrHelmFailure)
+	case "chart-pull-failed":
+		if strings.Contains(lowerReason, "not found") || strings.Contains(lowerReason, "404") {
+			return newFailure(ErrChartNotFound)
+		}
+	}
+	return nil
+}
+
+// appReleaseStatus is the release status of an App CR, used to ignore a failure reported before it was updated
+type appReleaseStatus struct {
+	status       string
+	reason       string
+	lastDeployed metav1.Time
+}
+
+func (s appReleaseStatus) equal(other appReleaseStatus) bool {
+	return s.status == other.status && s.reason == other.reason && s.lastDeployed.Equal(&other.lastDeployed)
+}
+
+// getAppReleaseStatus returns the current release status of the App CR, empty if it doesn't exist yet
+func getAppReleaseStatus(ctx context.Context, app *application.Application) appReleaseStatus {
+	appCR := &v1alpha1.App{}
+	err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: app.InstallName, Namespace: app.GetNamespace()}, appCR)
+	if err != nil {
+		return appReleaseStatus{}
+	}
+	return appReleaseStatusOf(appCR)
+}
+
+func appReleaseStatusOf(appCR *v1alpha1.App) appReleaseStatus {
+	return appReleaseStatus{
+		status:       appCR.Status.Release.Status,
+		reason:       appCR.Status.Release.Reason,
+		lastDeployed: appCR.Status.Release.LastDeployed,
+	}
+}
+
+// checkAppFailure returns an *AppFailedError if the App CR, or its Chart CR, reports a terminal failure.
+// The App status is ignored while it's the same as the given status from before the App was deployed, as it
+// isn't known to reflect the latest spec yet.
+func checkAppFailure(ctx context.Context, app *application.Application, previous appReleaseStatus) error {
+	appCR := &v1alpha1.App{}
+	err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: app.InstallName, Namespace: app.GetNamespace()}, appCR)
+	if err != nil {
+		return nil
+	}
+	if appReleaseStatusOf(appCR).equal(previous) {
+		return nil
+	}
+	if failure := GetAppFailure(appCR); failure != nil {
+		return failure
+	}
+
+	// The Chart CR can report a failure before app-operator has copied it into the App status
+	chartClient := cr.Client(state.GetFramework().MC())
+	if !app.InCluster && app.ClusterName != "" {
+		wcClient, err := state.GetFramework().WC(app.ClusterName)
+		if err != nil {
+			return nil
+		}
+		chartClient = wcClient
+	}
+	chart := &v1alpha1.Chart{}
+	chartName := strings.TrimPrefix(app.InstallName, fmt.Sprintf("%s-", app.ClusterName))
+	err = chartClient.Get(ctx, types.NamespacedName{Name: chartName, Namespace: "giantswarm"}, chart)
+	if err != nil || chart.Spec.Version != appCR.Spec.Version {
+		return nil
+	}
+	return GetChartFailure(chart)
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAppStatusFailure(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        string
+		reason        string
+		expectedError error
+	}{
+		{
+			name:          "deployed",
+			status:        "deployed",
+			expectedError: nil,
+		},
+		{
+			name:          "pending install",
+			status:        "pending-install",
+			expectedError: nil,
+		},
+		{
+			name:          "not installed yet",
+			status:        "not-installed",
+			expectedError: nil,
+		},
+		{
+			name:          "values schema violation",
+			status:        "not-installed",
+			reason:        "values don't meet the specifications of the schema(s) in the following chart(s):\nhello-world:\n- replicas: Invalid type. Expected: integer, given: string",
+			expectedError: ErrValuesSchemaViolation,
+		},
+		{
+			name:          "values schema violation status",
+			status:        "values-schema-violation",
+			reason:        "values don't match the schema",
+			expectedError: ErrValuesSchemaViolation,
+		},
+		{
+			name:          "chart not found",
+			status:        "chart-pull-failed",
+			reason:        "object storage error: failed to pull chart: 404 Not Found",
+			expectedError: ErrChartNotFound,
+		},
+		{
+			name:          "transient chart pull failure",
+			status:        "chart-pull-failed",
+			reason:        "dial tcp: i/o timeout",
+			expectedError: nil,
+		},
+		{
+			name:          "helm failure",
+			status:        "FAILED",
+			reason:        "rendered manifests contain a resource that already exists",
+			expectedError: ErrHelmFailure,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			failure := appStatusFailure("App", "hello-world", "org-test", tc.status, tc.reason)
+			if tc.expectedError == nil {
+				if failure != nil {
+					t.Fatalf("Expected no failure but got %v", failure)
+				}
+				return
+			}
+			if failure == nil {
+				t.Fatalf("Expected failure '%v' but got none", tc.expectedError)
+			}
+			if !errors.Is(failure, tc.expectedError) {
+				t.Fatalf("Expected failure '%v' but got '%v'", tc.expectedError, failure.Err)
+			}
+			if failure.Reason != tc.reason || failure.Status != tc.status {
+				t.Fatalf("Expected failure to carry status '%s' and reason '%s' but got '%s' and '%s'", tc.status, tc.reason, failure.Status, failure.Reason)
+			}
+		})
+	}
+}
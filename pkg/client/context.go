@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+)
+
+// withSuiteContext returns a copy of ctx that is also cancelled when the suite context from the state is
+// cancelled, ensuring waits are aborted on interrupts or the suite timeout even if the caller provided
+// an unrelated context.
+func withSuiteContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	suiteCtx := state.GetContext()
+	if suiteCtx == nil {
+		return ctx, cancel
+	}
+
+	stop := context.AfterFunc(suiteCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// retriesExceededReason is the reason helm-controller stalls a HelmRelease with once its remediation retries are used up
+	retriesExceededReason = "RetriesExceeded"
+	// chartPullErrorReason is the reason source-controller sets on a HelmChart it failed to pull
+	chartPullErrorReason = "ChartPullError"
+	// invalidChartReferenceReason is the reason source-controller sets on a HelmChart with an unresolvable chart or version
+	invalidChartReferenceReason = "InvalidChartReference"
+)
+
+// chartNotFoundMessages are the messages source-controller reports when a chart or chart version doesn't exist
+var chartNotFoundMessages = []string{
+	"not found",
+	"no chart name found",
+	"no chart version found",
+	"manifest_unknown",
+	"name_unknown",
+}
+
+// HelmReleaseFailedError is returned by the HelmRelease wait helpers when the HelmRelease, or its source, reports a
+// failure it won't recover from without a change, such as exhausted install / upgrade retries or a chart version
+// that doesn't exist.
+type HelmReleaseFailedError struct {
+	// Kind is the kind of the resource reporting the failure, e.g. `HelmRelease`, `OCIRepository` or `HelmChart`.
+	Kind string
+	// Name is the name of the resource reporting the failure.
+	Name string
+	// Namespace is the namespace of the resource reporting the failure.
+	Namespace string
+	// Reason is the Flux reason of the failed condition, e.g. `InstallFailed` or `RetriesExceeded`.
+	Reason string
+	// Message is the message of the failed condition.
+	Message string
+}
+
+func (e *HelmReleaseFailedError) Error() string {
+	return fmt.Sprintf("%s %s/%s failed with reason %s: %s", e.Kind, e.Namespace, e.Name, e.Reason, e.Message)
+}
+
+// GetHelmReleaseFailure returns a *HelmReleaseFailedError if the HelmRelease, or the source of its chart, reports a
+// terminal failure. Nil is returned while the HelmRelease may still become ready.
+func GetHelmReleaseFailure(ctx context.Context, c cr.Client, hr *helmv2.HelmRelease) error {
+	_ = sourcev1.AddToScheme(c.Scheme())
+	_ = sourcev1beta2.AddToScheme(c.Scheme())
+
+	if failure := helmReleaseFailure(hr); failure != nil {
+		return failure
+	}
+
+	switch {
+	case hr.Spec.ChartRef != nil && hr.Spec.ChartRef.Kind == string(SourceKindOCIRepository):
+		namespace := hr.Spec.ChartRef.Namespace
+		if namespace == "" {
+			namespace = hr.Namespace
+		}
+		source := &sourcev1beta2.OCIRepository{}
+		err := c.Get(ctx, types.NamespacedName{Name: hr.Spec.ChartRef.Name, Namespace: namespace}, source)
+		if err != nil {
+			return nil
+		}
+		if failure := sourceFailure(string(SourceKindOCIRepository), source.Name, source.Namespace, source.Generation, source.Status.Conditions); failure != nil {
+			return failure
+		}
+	case hr.Status.HelmChart != "":
+		namespace, name := hr.Status.GetHelmChart()
+		chart := &sourcev1.HelmChart{}
+		err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, chart)
+		if err != nil {
+			return nil
+		}
+		if failure := sourceFailure(sourcev1.HelmChartKind, chart.Name, chart.Namespace, chart.Generation, chart.Status.Conditions); failure != nil {
+			return failure
+		}
+	}
+
+	return nil
+}
+
+// helmReleaseFailure classifies the conditions of the HelmRelease, returning an error if its reconciliation has stalled,
+// the chart artifact can't be fetched, or the last install / upgrade failed and no remediation retries are left.
+// Conditions from before the latest spec change are ignored as helm-controller retries once the spec changes.
+func helmReleaseFailure(hr *helmv2.HelmRelease) *HelmReleaseFailedError {
+	newFailure := func(condition *metav1.Condition) *HelmReleaseFailedError {
+		return &HelmReleaseFailedError{
+			Kind:      helmv2.HelmReleaseKind,
+			Name:      hr.Name,
+			Namespace: hr.Namespace,
+			Reason:    condition.Reason,
+			Message:   condition.Message,
+		}
+	}
+
+	if stalled := apimeta.FindStatusCondition(hr.Status.Conditions, meta.StalledCondition); isCurrentCondition(stalled, hr.Generation, metav1.ConditionTrue) {
+		return newFailure(stalled)
+	}
+
+	ready := apimeta.FindStatusCondition(hr.Status.Conditions, meta.ReadyCondition)
+	if !isCurrentCondition(ready, hr.Generation, metav1.ConditionFalse) {
+		return nil
+	}
+	switch ready.Reason {
+	case retriesExceededReason, helmv2.ArtifactFailedReason:
+		return newFailure(ready)
+	case helmv2.InstallFailedReason, helmv2.UpgradeFailedReason:
+		if remediation := hr.GetActiveRemediation(); remediation != nil && remediation.RetriesExhausted(hr) {
+			return newFailure(ready)
+		}
+	}
+	return nil
+}
+
+// sourceFailure classifies the conditions of a source, returning an error if its reconciliation has stalled or the
+// chart it references can't be found.
+func sourceFailure(kind, name, namespace string, generation int64, conditions []metav1.Condition) *HelmReleaseFailedError {
+	newFailure := func(condition *metav1.Condition) *HelmReleaseFailedError {
+		return &HelmReleaseFailedError{
+			Kind:      kind,
+			Name:      name,
+			Namespace: namespace,
+			Reason:    condition.Reason,
+			Message:   condition.Message,
+		}
+	}
+
+	if stalled := apimeta.FindStatusCondition(conditions, meta.StalledCondition); isCurrentCondition(stalled, generation, metav1.ConditionTrue) {
+		return newFailure(stalled)
+	}
+
+	ready := apimeta.FindStatusCondition(conditions, meta.ReadyCondition)
+	if !isCurrentCondition(ready, generation, metav1.ConditionFalse) {
+		return nil
+	}
+	switch ready.Reason {
+	case sourcev1.OCIPullFailedReason, chartPullErrorReason, invalidChartReferenceReason:
+		message := strings.ToLower(ready.Message)
+		for _, notFound := range chartNotFoundMessages {
+			if strings.Contains(message, notFound) {
+				return newFailure(ready)
+			}
+		}
+	}
+	return nil
+}
+
+// isCurrentCondition returns if the condition is set with the given status for the current generation of the resource
+func isCurrentCondition(condition *metav1.Condition, generation int64, status metav1.ConditionStatus) bool {
+	return condition != nil && condition.Status == status && condition.ObservedGeneration >= generation
+}
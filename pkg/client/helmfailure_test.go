@@ -0,0 +1,197 @@
+package client
+
+import (
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHelmReleaseFailure(t *testing.T) {
+	tests := []struct {
+		name              string
+		generation        int64
+		retries           int
+		installFailures   int64
+		lastReleaseAction helmv2.ReleaseAction
+		conditions        []metav1.Condition
+		expectedFailure   bool
+		expectedReason    string
+		expectedMessage   string
+	}{
+		{
+			name:            "no conditions",
+			generation:      1,
+			expectedFailure: false,
+		},
+		{
+			name:       "stalled",
+			generation: 1,
+			conditions: []metav1.Condition{
+				{Type: meta.StalledCondition, Status: metav1.ConditionTrue, Reason: "RetriesExceeded", Message: "Failed to install after 1 attempt(s)", ObservedGeneration: 1},
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: "InstallFailed", Message: "install failed", ObservedGeneration: 1},
+			},
+			expectedFailure: true,
+			expectedReason:  "RetriesExceeded",
+			expectedMessage: "Failed to install after 1 attempt(s)",
+		},
+		{
+			name:       "retries exceeded",
+			generation: 1,
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: "RetriesExceeded", Message: "retries exceeded", ObservedGeneration: 1},
+			},
+			expectedFailure: true,
+			expectedReason:  "RetriesExceeded",
+		},
+		{
+			name:       "artifact failed",
+			generation: 1,
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: helmv2.ArtifactFailedReason, Message: "failed to get artifact", ObservedGeneration: 1},
+			},
+			expectedFailure: true,
+			expectedReason:  helmv2.ArtifactFailedReason,
+			expectedMessage: "failed to get artifact",
+		},
+		{
+			name:       "artifact failed for a previous generation",
+			generation: 2,
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: helmv2.ArtifactFailedReason, Message: "failed to get artifact", ObservedGeneration: 1},
+			},
+			expectedFailure: false,
+		},
+		{
+			name:              "install failed with retries left",
+			generation:        1,
+			retries:           3,
+			installFailures:   1,
+			lastReleaseAction: helmv2.ReleaseActionInstall,
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: helmv2.InstallFailedReason, Message: "install failed", ObservedGeneration: 1},
+			},
+			expectedFailure: false,
+		},
+		{
+			name:              "install failed with no retries left",
+			generation:        1,
+			retries:           3,
+			installFailures:   4,
+			lastReleaseAction: helmv2.ReleaseActionInstall,
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: helmv2.InstallFailedReason, Message: "install failed", ObservedGeneration: 1},
+			},
+			expectedFailure: true,
+			expectedReason:  helmv2.InstallFailedReason,
+			expectedMessage: "install failed",
+		},
+		{
+			name:              "failure from a previous generation",
+			generation:        2,
+			installFailures:   1,
+			lastReleaseAction: helmv2.ReleaseActionInstall,
+			conditions: []metav1.Condition{
+				{Type: meta.StalledCondition, Status: metav1.ConditionTrue, Reason: "RetriesExceeded", Message: "retries exceeded", ObservedGeneration: 1},
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: helmv2.InstallFailedReason, Message: "install failed", ObservedGeneration: 1},
+			},
+			expectedFailure: false,
+		},
+		{
+			name:       "blocked on dependency",
+			generation: 1,
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: meta.DependencyNotReadyReason, Message: "dependency not ready", ObservedGeneration: 1},
+			},
+			expectedFailure: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hr := &helmv2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Namespace: "org-test", Generation: tc.generation},
+				Spec: helmv2.HelmReleaseSpec{
+					Install: &helmv2.Install{Remediation: &helmv2.InstallRemediation{Retries: tc.retries}},
+				},
+				Status: helmv2.HelmReleaseStatus{
+					Conditions:                 tc.conditions,
+					InstallFailures:            tc.installFailures,
+					LastAttemptedReleaseAction: tc.lastReleaseAction,
+				},
+			}
+
+			failure := helmReleaseFailure(hr)
+			if (failure != nil) != tc.expectedFailure {
+				t.Fatalf("Expected failure to be %t but got %v", tc.expectedFailure, failure)
+			}
+			if failure == nil {
+				return
+			}
+			if failure.Kind != helmv2.HelmReleaseKind || failure.Name != "hello-world" || failure.Namespace != "org-test" {
+				t.Fatalf("Unexpected failed resource %s %s/%s", failure.Kind, failure.Namespace, failure.Name)
+			}
+			if failure.Reason != tc.expectedReason {
+				t.Fatalf("Expected reason '%s' but got '%s'", tc.expectedReason, failure.Reason)
+			}
+			if tc.expectedMessage != "" && failure.Message != tc.expectedMessage {
+				t.Fatalf("Expected message '%s' but got '%s'", tc.expectedMessage, failure.Message)
+			}
+		})
+	}
+}
+
+func TestSourceFailure(t *testing.T) {
+	tests := []struct {
+		name            string
+		conditions      []metav1.Condition
+		expectedFailure bool
+	}{
+		{
+			name: "ready",
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionTrue, Reason: "Succeeded", ObservedGeneration: 1},
+			},
+			expectedFailure: false,
+		},
+		{
+			name: "oci tag not found",
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: sourcev1.OCIPullFailedReason, Message: "failed to determine artifact digest: GET https://gsoci.azurecr.io/v2/charts/hello-world/manifests/9.9.9: MANIFEST_UNKNOWN: manifest tagged by \"9.9.9\" is not found", ObservedGeneration: 1},
+			},
+			expectedFailure: true,
+		},
+		{
+			name: "chart version not found",
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: "InvalidChartReference", Message: "invalid chart reference: failed to get chart version for remote reference: no chart version found for hello-world-9.9.9", ObservedGeneration: 1},
+			},
+			expectedFailure: true,
+		},
+		{
+			name: "transient pull failure",
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: sourcev1.OCIPullFailedReason, Message: "failed to pull artifact: connection reset by peer", ObservedGeneration: 1},
+			},
+			expectedFailure: false,
+		},
+		{
+			name: "stalled",
+			conditions: []metav1.Condition{
+				{Type: meta.StalledCondition, Status: metav1.ConditionTrue, Reason: sourcev1.URLInvalidReason, Message: "invalid URL", ObservedGeneration: 1},
+			},
+			expectedFailure: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			failure := sourceFailure("OCIRepository", "hello-world", "org-test", 1, tc.conditions)
+			if (failure != nil) != tc.expectedFailure {
+				t.Fatalf("Expected failure to be %t but got %v", tc.expectedFailure, failure)
+			}
+		})
+	}
+}
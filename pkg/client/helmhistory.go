@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+)
+
+// HelmReleaseRemediatedError is returned when helm-controller remediated a failed release of a HelmRelease,
+// e.g. by rolling back a failed upgrade, which otherwise can leave the HelmRelease reporting Ready on the old version.
+type HelmReleaseRemediatedError struct {
+	// Name is the name of the HelmRelease.
+	Name string
+	// Namespace is the namespace of the HelmRelease.
+	Namespace string
+	// Remediations are the rollback and uninstall remediation snapshots found in the release history.
+	Remediations []helmv2.Snapshot
+	// Reason is the reason of the HelmRelease's `Remediated` condition, if set.
+	Reason string
+	// Message is the message of the HelmRelease's `Remediated` condition, if set.
+	Message string
+}
+
+func (e *HelmReleaseRemediatedError) Error() string {
+	remediations := []string{}
+	for _, snapshot := range e.Remediations {
+		remediations = append(remediations, fmt.Sprintf("%s to revision %d (chart version %s)", snapshot.Action, snapshot.Version, snapshot.ChartVersion))
+	}
+	message := fmt.Sprintf("HelmRelease %s/%s was remediated", e.Namespace, e.Name)
+	if len(remediations) > 0 {
+		message += ": " + strings.Join(remediations, ", ")
+	}
+	if e.Reason != "" {
+		message += fmt.Sprintf(" (%s: %s)", e.Reason, e.Message)
+	}
+	return message
+}
+
+// GetHelmReleaseHistory returns the release history of a HelmRelease, as tracked by helm-controller in
+// status.history, sorted from the newest to the oldest release.
+func GetHelmReleaseHistory(ctx context.Context, name, namespace string) (helmv2.Snapshots, error) {
+	hr := &helmv2.HelmRelease{}
+	err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, hr)
+	if err != nil {
+		return nil, err
+	}
+
+	history := hr.Status.History
+	history.SortByVersion()
+	return history, nil
+}
+
+// GetLatestHelmReleaseRevision returns the revision of the latest release of a HelmRelease, or 0 if it has none.
+func GetLatestHelmReleaseRevision(ctx context.Context, name, namespace string) (int, error) {
+	history, err := GetHelmReleaseHistory(ctx, name, namespace)
+	if err != nil {
+		return 0, err
+	}
+	if latest := history.Latest(); latest != nil {
+		return latest.Version, nil
+	}
+	return 0, nil
+}
+
+// CheckHelmReleaseRemediation returns a *HelmReleaseRemediatedError if helm-controller rolled back or uninstalled
+// a release of the HelmRelease after the given revision, e.g. the revision before starting an upgrade.
+func CheckHelmReleaseRemediation(ctx context.Context, name, namespace string, sinceRevision int) error {
+	hr := &helmv2.HelmRelease{}
+	err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, hr)
+	if err != nil {
+		return err
+	}
+	if remediated := helmReleaseRemediation(hr, sinceRevision); remediated != nil {
+		return remediated
+	}
+	return nil
+}
+
+// helmReleaseRemediation returns an error if the history of the HelmRelease has rollback or uninstall remediation
+// snapshots newer than the given revision, or it has a `Remediated` condition. helm-controller removes the condition
+// once a release succeeds, so its presence means no release has succeeded since the remediation.
+func helmReleaseRemediation(hr *helmv2.HelmRelease, sinceRevision int) *HelmReleaseRemediatedError {
+	remediated := &HelmReleaseRemediatedError{Name: hr.Name, Namespace: hr.Namespace}
+
+	for _, snapshot := range hr.Status.History {
+		if snapshot == nil || snapshot.Version <= sinceRevision {
+			continue
+		}
+		switch snapshot.Action {
+		case helmv2.ReleaseActionRollback, helmv2.ReleaseActionUninstallRemediation:
+			remediated.Remediations = append(remediated.Remediations, *snapshot)
+		}
+	}
+
+	if condition := apimeta.FindStatusCondition(hr.Status.Conditions, helmv2.RemediatedCondition); condition != nil {
+		remediated.Reason = condition.Reason
+		remediated.Message = condition.Message
+	}
+
+	if len(remediated.Remediations) == 0 && remediated.Reason == "" {
+		return nil
+	}
+	return remediated
+}
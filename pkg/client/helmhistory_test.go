@@ -0,0 +1,98 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHelmReleaseRemediation(t *testing.T) {
+	tests := []struct {
+		name                 string
+		history              helmv2.Snapshots
+		conditions           []metav1.Condition
+		sinceRevision        int
+		expectedRemediated   bool
+		expectedRemediations int
+	}{
+		{
+			name: "successful upgrade",
+			history: helmv2.Snapshots{
+				{Version: 2, Status: "deployed", Action: helmv2.ReleaseActionUpgrade, ChartVersion: "1.1.0"},
+				{Version: 1, Status: "superseded", Action: helmv2.ReleaseActionInstall, ChartVersion: "1.0.0"},
+			},
+			sinceRevision:      1,
+			expectedRemediated: false,
+		},
+		{
+			name: "upgrade rolled back",
+			history: helmv2.Snapshots{
+				{Version: 3, Status: "deployed", Action: helmv2.ReleaseActionRollback, ChartVersion: "1.0.0"},
+				{Version: 2, Status: "failed", Action: helmv2.ReleaseActionUpgrade, ChartVersion: "1.1.0"},
+				{Version: 1, Status: "superseded", Action: helmv2.ReleaseActionInstall, ChartVersion: "1.0.0"},
+			},
+			conditions: []metav1.Condition{
+				{Type: helmv2.RemediatedCondition, Status: metav1.ConditionTrue, Reason: helmv2.RollbackSucceededReason, Message: "Helm rollback to previous release succeeded"},
+			},
+			sinceRevision:        1,
+			expectedRemediated:   true,
+			expectedRemediations: 1,
+		},
+		{
+			name: "rollback before the upgrade",
+			history: helmv2.Snapshots{
+				{Version: 4, Status: "deployed", Action: helmv2.ReleaseActionUpgrade, ChartVersion: "1.1.0"},
+				{Version: 3, Status: "superseded", Action: helmv2.ReleaseActionRollback, ChartVersion: "1.0.0"},
+			},
+			sinceRevision:      3,
+			expectedRemediated: false,
+		},
+		{
+			name: "upgrade uninstalled",
+			history: helmv2.Snapshots{
+				{Version: 2, Status: "uninstalled", Action: helmv2.ReleaseActionUninstallRemediation, ChartVersion: "1.1.0"},
+			},
+			sinceRevision:        1,
+			expectedRemediated:   true,
+			expectedRemediations: 1,
+		},
+		{
+			name:    "remediated condition only",
+			history: helmv2.Snapshots{},
+			conditions: []metav1.Condition{
+				{Type: helmv2.RemediatedCondition, Status: metav1.ConditionTrue, Reason: helmv2.UninstallSucceededReason, Message: "Helm uninstall succeeded"},
+			},
+			sinceRevision:        1,
+			expectedRemediated:   true,
+			expectedRemediations: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hr := &helmv2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Namespace: "org-test"},
+				Status: helmv2.HelmReleaseStatus{
+					History:    tc.history,
+					Conditions: tc.conditions,
+				},
+			}
+
+			remediated := helmReleaseRemediation(hr, tc.sinceRevision)
+			if (remediated != nil) != tc.expectedRemediated {
+				t.Fatalf("Expected remediated to be %t but got %v", tc.expectedRemediated, remediated)
+			}
+			if remediated == nil {
+				return
+			}
+			if len(remediated.Remediations) != tc.expectedRemediations {
+				t.Fatalf("Expected %d remediations but got %d", tc.expectedRemediations, len(remediated.Remediations))
+			}
+			if !strings.Contains(remediated.Error(), "org-test/hello-world") {
+				t.Fatalf("Expected error to reference the HelmRelease but got '%s'", remediated.Error())
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/giantswarm/clustertest/v5/pkg/logger"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	cr "sigs.k8s.io/controller-runtime/pkg/client"
@@ -68,29 +70,78 @@ type HelmReleaseConfig struct {
 	// When set, the framework creates the source CR before installing the HelmRelease.
 	// If empty, the source CR must already exist in the cluster.
 	SourceURL string
+	// SourceAuth configures authentication (credentials, CA bundle, OIDC provider) and signature verification
+	// of the source created from SourceURL. Optional.
+	SourceAuth *SourceAuth
+	// SourceDigest pins the OCIRepository created from SourceURL to the given manifest digest
+	// (`sha256:...`) instead of the ChartVersion tag. Only supported for SourceKindOCIRepository.
+	SourceDigest string
+	// SourceInsecure allows the OCIRepository created from SourceURL to be pulled over plain HTTP.
+	SourceInsecure bool
 	// Values is the raw values YAML to pass to the chart.
+	// It is provided via a generated `{name}-values` Secret that is applied after any ValuesFrom layers.
 	Values string
+	// SecretValues is raw values YAML containing sensitive values, e.g. API tokens.
+	// It is provided via a generated `{name}-secret-values` Secret that is applied after Values.
+	SecretValues string
+	// ValuesFrom lists ConfigMaps and Secrets to merge into the values, in order, via spec.valuesFrom.
+	ValuesFrom []ValuesLayer
+	// ValuesOverrides lists ConfigMaps and Secrets to merge into the values, in order, after Values but before
+	// SecretValues, e.g. to mirror App CR extra configs with a priority above the user values.
+	ValuesOverrides []ValuesLayer
+	// PostRenderers are applied to the rendered manifests before they are installed, via spec.postRenderers.
+	PostRenderers []helmv2.PostRenderer
 	// Interval is the reconciliation interval. Defaults to 5m.
 	Interval time.Duration
 	// Timeout is the time to wait for Helm operations. Defaults to 5m.
 	Timeout time.Duration
 	// Retries is the number of retries for install/upgrade remediation. Defaults to 10.
 	Retries *int
+	// RemediationStrategy is the strategy used to remediate a failed upgrade (`rollback` or `uninstall`).
+	// Defaults to helmv2.RollbackRemediationStrategy.
+	RemediationStrategy helmv2.RemediationStrategy
+	// UpgradeRetries is the number of retries for upgrade remediation. Defaults to Retries.
+	UpgradeRetries *int
 	// ServiceAccountName is the Kubernetes service account to impersonate when reconciling.
 	// Required by clusters with the flux-multi-tenancy Kyverno policy.
 	ServiceAccountName string
 	// KubeConfigSecretName is the name of the secret containing kubeconfig for remote cluster access.
 	// Required when deploying to a workload cluster from the management cluster.
 	KubeConfigSecretName string
+	// DependsOn lists the HelmReleases that must be ready before this HelmRelease is reconciled.
+	// If a namespace isn't set the dependency is expected in the same namespace as this HelmRelease.
+	DependsOn []helmv2.DependencyReference
+	// EnableTests has helm-controller run the chart's test hooks after every install and upgrade, via spec.test.
+	EnableTests bool
+}
+
+// ValuesLayer is a ConfigMap or Secret referenced by a HelmRelease's spec.valuesFrom.
+type ValuesLayer struct {
+	// Kind is either `ConfigMap` or `Secret`.
+	Kind string
+	// Name of the ConfigMap or Secret, in the same namespace as the HelmRelease.
+	Name string
+	// ValuesKey is the data key containing the values. Defaults to `values.yaml`.
+	ValuesKey string
+	// TargetPath is the YAML dot notation path the value is merged at. If empty the values are merged at the root.
+	TargetPath string
+	// Optional marks the layer as optional, so a missing ConfigMap, Secret or key is ignored.
+	Optional bool
+	// Values is the content to create or update the ConfigMap or Secret with before installing.
+	// If empty the ConfigMap or Secret is expected to already exist, or be created by your tests.
+	Values string
 }
 
 // InstallHelmRelease creates a HelmRelease CR and waits for it to become ready.
 // It ensures the HelmRelease namespace exists on the MC. Target and storage namespaces
 // are created by Flux via spec.install.createNamespace.
-// Timeout can be controlled via the provided context.
+// Timeout can be controlled via the provided context. The wait is also aborted if the suite context is cancelled.
 func InstallHelmRelease(ctx context.Context, cfg HelmReleaseConfig) {
 	GinkgoHelper()
 
+	ctx, cancel := withSuiteContext(ctx)
+	defer cancel()
+
 	if cfg.Interval == 0 {
 		cfg.Interval = 5 * time.Minute
 	}
@@ -113,20 +164,30 @@ func InstallHelmRelease(ctx context.Context, cfg HelmReleaseConfig) {
 	}
 
 	if cfg.Values != "" {
-		createValuesSecret(ctx, cfg.Name, cfg.Namespace, cfg.Values)
+		createValuesSecret(ctx, fmt.Sprintf("%s-values", cfg.Name), cfg.Namespace, cfg.Values)
+	}
+
+	if cfg.SecretValues != "" {
+		createValuesSecret(ctx, fmt.Sprintf("%s-secret-values", cfg.Name), cfg.Namespace, cfg.SecretValues)
+	}
+
+	for _, layer := range valuesLayers(cfg) {
+		if layer.Values != "" {
+			ensureValuesLayer(ctx, cfg.Namespace, layer)
+		}
 	}
 
 	hr := buildHelmRelease(cfg)
 	logger.Log("Installing HelmRelease %s/%s (chart: %s, version: %s, source: %s/%s)",
 		hr.Namespace, hr.Name, cfg.ChartName, cfg.ChartVersion, cfg.SourceKind, cfg.SourceName)
 
-	err := state.GetFramework().MC().CreateOrUpdate(state.GetContext(), hr)
+	err := state.GetFramework().MC().CreateOrUpdate(ctx, hr)
 	Expect(err).NotTo(HaveOccurred())
 
 	state.SetHelmRelease(hr)
 
 	Eventually(func() (bool, error) {
-		return IsHelmReleaseReady(state.GetContext(), hr.Name, hr.Namespace)
+		return IsHelmReleaseReady(ctx, hr.Name, hr.Namespace)
 	}).
 		WithContext(ctx).
 		WithPolling(5 * time.Second).
@@ -135,6 +196,9 @@ func InstallHelmRelease(ctx context.Context, cfg HelmReleaseConfig) {
 
 // IsHelmReleaseReady checks if a HelmRelease has the Ready condition set to True.
 // The current status is logged on each call, mirroring the App CR wait conditions.
+// If the HelmRelease is blocked waiting on one of its `dependsOn` HelmReleases this is logged as well.
+// If the HelmRelease or its source reports a terminal failure a *HelmReleaseFailedError is returned, wrapped so
+// that Gomega's Eventually stops polling immediately (use errors.As to inspect it).
 func IsHelmReleaseReady(ctx context.Context, name, namespace string) (bool, error) {
 	ready, err := helmrelease.IsHelmReleaseReady(ctx, state.GetFramework().MC(), name, namespace)()
 	if err != nil {
@@ -144,25 +208,64 @@ func IsHelmReleaseReady(ctx context.Context, name, namespace string) (bool, erro
 		}
 		return false, err
 	}
+
+	if !ready {
+		hr := &helmv2.HelmRelease{}
+		err = state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, hr)
+		if err == nil {
+			if message, blocked := dependencyNotReadyMessage(hr); blocked {
+				logger.Log("HelmRelease '%s/%s' is blocked waiting on a dependency: %s", namespace, name, message)
+			}
+			if failure := GetHelmReleaseFailure(ctx, state.GetFramework().MC(), hr); failure != nil {
+				logger.Log("HelmRelease '%s/%s' has failed: %v", namespace, name, failure)
+				return false, StopTrying("HelmRelease has a terminal failure").Wrap(failure)
+			}
+		}
+	}
+
 	return ready, nil
 }
 
+// dependencyNotReadyMessage returns the Ready condition message if the HelmRelease isn't being
+// reconciled because one of its `dependsOn` HelmReleases isn't ready yet.
+func dependencyNotReadyMessage(hr *helmv2.HelmRelease) (string, bool) {
+	condition := apimeta.FindStatusCondition(hr.Status.Conditions, meta.ReadyCondition)
+	if condition == nil || condition.Status == metav1.ConditionTrue || condition.Reason != meta.DependencyNotReadyReason {
+		return "", false
+	}
+	return condition.Message, true
+}
+
 // IsAllHelmReleasesReady returns a check function for use with Gomega's Eventually
 // that polls the given list of HelmReleases and returns true once all of them
 // have a Ready=True condition. Its signature mirrors wait.IsAllAppDeployed so
 // call-sites can use either one interchangeably.
 // Every HelmRelease is checked and logged on each poll, so a stuck release is visible.
+// Polling stops with a *HelmReleaseFailedError as soon as any of them reports a terminal failure.
 func IsAllHelmReleasesReady(ctx context.Context, c cr.Client, helmReleases []types.NamespacedName) func() (bool, error) {
 	areAllReady := helmrelease.AreAllReady(ctx, c, helmReleases)
 	return func() (bool, error) {
-		return areAllReady() == nil, nil
+		if areAllReady() == nil {
+			return true, nil
+		}
+
+		for _, namespacedName := range helmReleases {
+			hr := &helmv2.HelmRelease{}
+			if err := c.Get(ctx, namespacedName, hr); err != nil {
+				continue
+			}
+			if failure := GetHelmReleaseFailure(ctx, c, hr); failure != nil {
+				logger.Log("HelmRelease '%s' has failed: %v", namespacedName, failure)
+				return false, StopTrying("HelmRelease has a terminal failure").Wrap(failure)
+			}
+		}
+		return false, nil
 	}
 }
 
 // IsHelmReleaseVersion checks if a HelmRelease has the expected chart version in its status history.
 func IsHelmReleaseVersion(ctx context.Context, name, namespace, version string) (bool, error) {
-	hr := &helmv2.HelmRelease{}
-	err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, hr)
+	actualVersion, err := GetHelmReleaseVersion(ctx, name, namespace)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return false, nil
@@ -170,21 +273,32 @@ func IsHelmReleaseVersion(ctx context.Context, name, namespace, version string)
 		return false, err
 	}
 
+	if actualVersion == "" {
+		logger.Log("HelmRelease version for '%s' is not yet known: expectedVersion='%s'", name, version)
+		return false, nil
+	}
+
+	return logHelmReleaseVersion(name, strings.TrimPrefix(version, "v"), actualVersion), nil
+}
+
+// GetHelmReleaseVersion returns the chart version of a HelmRelease.
+// For HelmRepository sources this is the version set in spec.chart, for OCIRepository sources
+// this is the last attempted revision. Returns an empty string if the version isn't known yet.
+func GetHelmReleaseVersion(ctx context.Context, name, namespace string) (string, error) {
+	hr := &helmv2.HelmRelease{}
+	err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, hr)
+	if err != nil {
+		return "", err
+	}
+
 	// Check spec.chart if using HelmRepository source
 	if hr.Spec.Chart != nil {
-		return logHelmReleaseVersion(name, version, hr.Spec.Chart.Spec.Version), nil
+		return strings.TrimPrefix(hr.Spec.Chart.Spec.Version, "v"), nil
 	}
 
 	// For OCIRepository sources, check the last attempted revision in status.
-	// Flux appends a +<oci-digest> suffix (e.g. 0.0.1-abc123+4ef3415e2070) and
-	// version may carry a v prefix, so normalise both sides before comparing.
-	if hr.Status.LastAttemptedRevision != "" {
-		rev := strings.SplitN(hr.Status.LastAttemptedRevision, "+", 2)[0]
-		return logHelmReleaseVersion(name, strings.TrimPrefix(version, "v"), rev), nil
-	}
-
-	logger.Log("HelmRelease version for '%s' is not yet known: expectedVersion='%s'", name, version)
-	return false, nil
+	// Flux appends a +<oci-digest> suffix (e.g. 0.0.1-abc123+4ef3415e2070) so strip it off.
+	return strings.SplitN(hr.Status.LastAttemptedRevision, "+", 2)[0], nil
 }
 
 // logHelmReleaseVersion logs the version comparison and reports whether it matches.
@@ -197,7 +311,7 @@ func logHelmReleaseVersion(name, expectedVersion, actualVersion string) bool {
 	return false
 }
 
-// DeleteHelmRelease deletes a HelmRelease CR and its associated values Secret if present.
+// DeleteHelmRelease deletes a HelmRelease CR and its associated values Secrets if present.
 func DeleteHelmRelease(ctx context.Context, name, namespace string) error {
 	hr := &helmv2.HelmRelease{
 		ObjectMeta: metav1.ObjectMeta{
@@ -210,17 +324,18 @@ func DeleteHelmRelease(ctx context.Context, name, namespace string) error {
 		return fmt.Errorf("deleting HelmRelease %s/%s: %w", namespace, name, err)
 	}
 
-	// Clean up the values secret if it was created
-	secretName := fmt.Sprintf("%s-values", name)
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: namespace,
-		},
-	}
-	err = state.GetFramework().MC().Delete(ctx, secret)
-	if err != nil && !errors.IsNotFound(err) {
-		return fmt.Errorf("deleting values Secret %s/%s: %w", namespace, secretName, err)
+	// Clean up the values secrets if they were created
+	for _, secretName := range []string{fmt.Sprintf("%s-values", name), fmt.Sprintf("%s-secret-values", name)} {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: namespace,
+			},
+		}
+		err = state.GetFramework().MC().Delete(ctx, secret)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("deleting values Secret %s/%s: %w", namespace, secretName, err)
+		}
 	}
 
 	return nil
@@ -267,7 +382,7 @@ func DeleteHelmSource(ctx context.Context, cfg HelmReleaseConfig) error {
 	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("deleting %s %s/%s: %w", sourceKind, sourceNamespace, sourceName, err)
 	}
-	return nil
+	return deleteSourceAuthSecrets(ctx, sourceName, sourceNamespace, cfg.SourceAuth)
 }
 
 // ensureHelmSource creates the source CR (HelmRepository or OCIRepository) if SourceURL is set.
@@ -285,19 +400,7 @@ func ensureHelmSource(ctx context.Context, cfg HelmReleaseConfig) {
 		sourceKind = SourceKindOCIRepository
 	}
 
-	sourceURL := cfg.SourceURL
-	if sourceURL == "" {
-		switch sourceKind {
-		case SourceKindHelmRepository:
-			sourceURL = DefaultGiantSwarmHelmRepositoryURL
-		case SourceKindOCIRepository:
-			chartName := cfg.ChartName
-			if chartName != "" {
-				sourceURL = DefaultGiantSwarmHelmRepositoryURL + "/" + chartName
-			}
-		}
-	}
-
+	sourceURL := helmSourceURL(cfg, sourceKind)
 	if sourceURL == "" {
 		return
 	}
@@ -311,17 +414,51 @@ func ensureHelmSource(ctx context.Context, cfg HelmReleaseConfig) {
 		sourceNamespace = cfg.Namespace
 	}
 
+	authRefs := ensureSourceAuth(ctx, sourceName, sourceNamespace, sourceURL, cfg.SourceAuth)
+
+	switch sourceKind {
+	case SourceKindHelmRepository:
+		ensureHelmRepository(ctx, sourceName, sourceNamespace, sourceURL, authRefs)
+	case SourceKindOCIRepository:
+		ensureOCIRepository(ctx, sourceName, sourceNamespace, sourceURL, cfg.ChartVersion, cfg.SourceDigest, cfg.SourceInsecure, authRefs)
+	}
+}
+
+// helmSourceURL returns the URL of the source of the HelmRelease config, defaulting to the Giant Swarm catalog
+func helmSourceURL(cfg HelmReleaseConfig, sourceKind SourceKind) string {
+	if cfg.SourceURL != "" {
+		return cfg.SourceURL
+	}
 	switch sourceKind {
 	case SourceKindHelmRepository:
-		ensureHelmRepository(ctx, sourceName, sourceNamespace, sourceURL)
+		return DefaultGiantSwarmHelmRepositoryURL
 	case SourceKindOCIRepository:
-		ensureOCIRepository(ctx, sourceName, sourceNamespace, sourceURL, cfg.ChartVersion)
+		if cfg.ChartName != "" {
+			return DefaultGiantSwarmHelmRepositoryURL + "/" + cfg.ChartName
+		}
 	}
+	return ""
+}
+
+// helmChartVerification returns the verification to set on the HelmChart generated for a HelmRepository source
+func helmChartVerification(auth *SourceAuth, sourceName string) *helmv2.HelmChartTemplateVerification {
+	if auth == nil || auth.Verify == nil {
+		return nil
+	}
+
+	verify := &helmv2.HelmChartTemplateVerification{
+		Provider:  auth.Verify.Provider,
+		SecretRef: auth.Verify.SecretRef,
+	}
+	if verify.SecretRef == nil && len(auth.VerifyFiles) > 0 {
+		verify.SecretRef = &meta.LocalObjectReference{Name: sourceName + "-verify"}
+	}
+	return verify
 }
 
 // ensureHelmRepository creates a HelmRepository if it doesn't already exist.
 // For OCI-hosted Helm charts, pass an "oci://" URL; for HTTP/HTTPS catalogs pass an https URL.
-func ensureHelmRepository(ctx context.Context, name, namespace, url string) {
+func ensureHelmRepository(ctx context.Context, name, namespace, url string, authRefs sourceAuthRefs) {
 	GinkgoHelper()
 
 	repoType := sourcev1.HelmRepositoryTypeDefault
@@ -335,11 +472,17 @@ func ensureHelmRepository(ctx context.Context, name, namespace, url string) {
 			Namespace: namespace,
 		},
 		Spec: sourcev1.HelmRepositorySpec{
-			Type:     repoType,
-			URL:      url,
-			Interval: metav1.Duration{Duration: 5 * time.Minute},
+			Type:          repoType,
+			URL:           url,
+			Interval:      metav1.Duration{Duration: 5 * time.Minute},
+			SecretRef:     authRefs.secretRef,
+			CertSecretRef: authRefs.certSecretRef,
 		},
 	}
+	if repoType == sourcev1.HelmRepositoryTypeOCI {
+		// The provider is only supported for OCI HelmRepositories
+		obj.Spec.Provider = authRefs.provider
+	}
 
 	logger.Log("Ensuring HelmRepository %s/%s (url: %s)", namespace, name, url)
 	err := state.GetFramework().MC().Create(ctx, obj)
@@ -350,7 +493,8 @@ func ensureHelmRepository(ctx context.Context, name, namespace, url string) {
 
 // ensureOCIRepository creates an OCIRepository if it doesn't already exist.
 // The tag is set to chartVersion; pass an empty chartVersion to use "latest".
-func ensureOCIRepository(ctx context.Context, name, namespace, url, tag string) {
+// If digest is set it takes precedence over the tag.
+func ensureOCIRepository(ctx context.Context, name, namespace, url, tag, digest string, insecure bool, authRefs sourceAuthRefs) {
 	GinkgoHelper()
 
 	tag = strings.TrimPrefix(tag, "v")
@@ -367,38 +511,69 @@ func ensureOCIRepository(ctx context.Context, name, namespace, url, tag string)
 			URL:      url,
 			Interval: metav1.Duration{Duration: 5 * time.Minute},
 			Reference: &sourcev1beta2.OCIRepositoryRef{
-				Tag: tag,
+				Tag:    tag,
+				Digest: digest,
 			},
+			SecretRef:     authRefs.secretRef,
+			CertSecretRef: authRefs.certSecretRef,
+			Provider:      authRefs.provider,
+			Verify:        authRefs.verify,
+			Insecure:      insecure,
 		},
 	}
 
-	logger.Log("Ensuring OCIRepository %s/%s (url: %s, tag: %s)", namespace, name, url, tag)
+	logger.Log("Ensuring OCIRepository %s/%s (url: %s, tag: %s, digest: %s)", namespace, name, url, tag, digest)
 	err := state.GetFramework().MC().Create(ctx, obj)
 	if err != nil && !errors.IsAlreadyExists(err) {
 		Expect(err).NotTo(HaveOccurred())
 	}
 }
 
-// updateOCIRepositoryTag patches the spec.ref.tag of an existing OCIRepository.
-func updateOCIRepositoryTag(ctx context.Context, name, namespace, tag string) {
+// updateOCIRepositoryRef patches the spec.ref of an existing OCIRepository to the given tag, or the digest of the
+// config if set. See setOCIRepositoryRef for when spec.url is also updated.
+func updateOCIRepositoryRef(ctx context.Context, name, namespace string, cfg HelmReleaseConfig, tag string) {
 	GinkgoHelper()
 
 	_ = sourcev1beta2.AddToScheme(state.GetFramework().MC().Scheme())
 
-	tag = strings.TrimPrefix(tag, "v")
-
 	obj := &sourcev1beta2.OCIRepository{}
 	err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj)
 	Expect(err).NotTo(HaveOccurred())
 
+	setOCIRepositoryRef(obj, cfg, tag, os.Getenv(EnvLocalChartRegistry))
+
+	if cfg.SourceDigest != "" {
+		logger.Log("Updating OCIRepository %s/%s to %s@%s", namespace, name, obj.Spec.URL, cfg.SourceDigest)
+	} else {
+		logger.Log("Updating OCIRepository %s/%s to %s:%s", namespace, name, obj.Spec.URL, obj.Spec.Reference.Tag)
+	}
+	err = state.GetFramework().MC().Update(ctx, obj, &cr.UpdateOptions{})
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// setOCIRepositoryRef sets the ref of the OCIRepository to the given tag and the digest of the config. The URL is
+// only changed if set in the config, or if the OCIRepository points at a local chart pushed to localRegistry,
+// in which case the published source is restored. Otherwise the URL of existing sources is kept as is.
+func setOCIRepositoryRef(obj *sourcev1beta2.OCIRepository, cfg HelmReleaseConfig, tag, localRegistry string) {
+	switch {
+	case cfg.SourceURL != "":
+		obj.Spec.URL = cfg.SourceURL
+		obj.Spec.Insecure = cfg.SourceInsecure
+	case isLocalChartURL(obj.Spec.URL, localRegistry):
+		obj.Spec.URL = helmSourceURL(cfg, SourceKindOCIRepository)
+		obj.Spec.Insecure = false
+	}
 	if obj.Spec.Reference == nil {
 		obj.Spec.Reference = &sourcev1beta2.OCIRepositoryRef{}
 	}
-	obj.Spec.Reference.Tag = tag
+	obj.Spec.Reference.Tag = strings.TrimPrefix(tag, "v")
+	obj.Spec.Reference.Digest = cfg.SourceDigest
+}
 
-	logger.Log("Updating OCIRepository %s/%s tag to %s", namespace, name, tag)
-	err = state.GetFramework().MC().Update(ctx, obj, &cr.UpdateOptions{})
-	Expect(err).NotTo(HaveOccurred())
+// isLocalChartURL returns true if the OCI URL is of a chart pushed to the local chart registry
+func isLocalChartURL(url, localRegistry string) bool {
+	registry := strings.TrimSuffix(strings.TrimPrefix(localRegistry, "oci://"), "/")
+	return registry != "" && strings.HasPrefix(strings.TrimPrefix(url, "oci://"), registry+"/")
 }
 
 func buildHelmRelease(cfg HelmReleaseConfig) *helmv2.HelmRelease {
@@ -422,6 +597,16 @@ func buildHelmRelease(cfg HelmReleaseConfig) *helmv2.HelmRelease {
 		retries = *cfg.Retries
 	}
 
+	upgradeRetries := retries
+	if cfg.UpgradeRetries != nil {
+		upgradeRetries = *cfg.UpgradeRetries
+	}
+
+	remediationStrategy := cfg.RemediationStrategy
+	if remediationStrategy == "" {
+		remediationStrategy = helmv2.RollbackRemediationStrategy
+	}
+
 	hr := &helmv2.HelmRelease{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "helm.toolkit.fluxcd.io/v2",
@@ -441,8 +626,8 @@ func buildHelmRelease(cfg HelmReleaseConfig) *helmv2.HelmRelease {
 			},
 			Upgrade: &helmv2.Upgrade{
 				Remediation: &helmv2.UpgradeRemediation{
-					Retries:              retries,
-					Strategy:             &[]helmv2.RemediationStrategy{helmv2.RollbackRemediationStrategy}[0],
+					Retries:              upgradeRetries,
+					Strategy:             &remediationStrategy,
 					RemediateLastFailure: boolPtr(true),
 				},
 			},
@@ -470,6 +655,7 @@ func buildHelmRelease(cfg HelmReleaseConfig) *helmv2.HelmRelease {
 					Name:      sourceName,
 					Namespace: sourceNamespace,
 				},
+				Verify: helmChartVerification(cfg.SourceAuth, sourceName),
 			},
 		}
 	}
@@ -490,6 +676,22 @@ func buildHelmRelease(cfg HelmReleaseConfig) *helmv2.HelmRelease {
 		hr.Spec.ServiceAccountName = cfg.ServiceAccountName
 	}
 
+	if cfg.EnableTests {
+		hr.Spec.Test = &helmv2.Test{Enable: true}
+	}
+
+	if len(cfg.PostRenderers) > 0 {
+		hr.Spec.PostRenderers = cfg.PostRenderers
+	}
+
+	if len(cfg.DependsOn) > 0 {
+		hr.Spec.DependsOn = cfg.DependsOn
+	}
+
+	for _, layer := range cfg.ValuesFrom {
+		hr.Spec.ValuesFrom = append(hr.Spec.ValuesFrom, valuesReference(layer))
+	}
+
 	if cfg.Values != "" {
 		hr.Spec.ValuesFrom = append(hr.Spec.ValuesFrom, helmv2.ValuesReference{
 			Kind: "Secret",
@@ -497,6 +699,17 @@ func buildHelmRelease(cfg HelmReleaseConfig) *helmv2.HelmRelease {
 		})
 	}
 
+	for _, layer := range cfg.ValuesOverrides {
+		hr.Spec.ValuesFrom = append(hr.Spec.ValuesFrom, valuesReference(layer))
+	}
+
+	if cfg.SecretValues != "" {
+		hr.Spec.ValuesFrom = append(hr.Spec.ValuesFrom, helmv2.ValuesReference{
+			Kind: "Secret",
+			Name: fmt.Sprintf("%s-secret-values", cfg.Name),
+		})
+	}
+
 	if cfg.KubeConfigSecretName != "" {
 		hr.Spec.KubeConfig = &meta.KubeConfigReference{
 			SecretRef: &meta.SecretKeyReference{
@@ -508,7 +721,24 @@ func buildHelmRelease(cfg HelmReleaseConfig) *helmv2.HelmRelease {
 	return hr
 }
 
-// createValuesSecret creates a Secret containing chart values for a HelmRelease.
+// valuesLayers returns all ValuesFrom and ValuesOverrides layers of the HelmRelease
+func valuesLayers(cfg HelmReleaseConfig) []ValuesLayer {
+	layers := append([]ValuesLayer{}, cfg.ValuesFrom...)
+	return append(layers, cfg.ValuesOverrides...)
+}
+
+// valuesReference returns the spec.valuesFrom reference of a values layer
+func valuesReference(layer ValuesLayer) helmv2.ValuesReference {
+	return helmv2.ValuesReference{
+		Kind:       layer.Kind,
+		Name:       layer.Name,
+		ValuesKey:  layer.ValuesKey,
+		TargetPath: layer.TargetPath,
+		Optional:   layer.Optional,
+	}
+}
+
+// createValuesSecret creates a Secret with the given name containing chart values for a HelmRelease.
 // It ensures the target namespace exists before creating the Secret.
 func createValuesSecret(ctx context.Context, name, namespace, values string) {
 	GinkgoHelper()
@@ -521,7 +751,7 @@ func createValuesSecret(ctx context.Context, name, namespace, values string) {
 			Kind:       "Secret",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-values", name),
+			Name:      name,
 			Namespace: namespace,
 		},
 		StringData: map[string]string{
@@ -533,9 +763,64 @@ func createValuesSecret(ctx context.Context, name, namespace, values string) {
 	Expect(err).NotTo(HaveOccurred())
 }
 
+// ensureValuesLayer creates or updates the ConfigMap or Secret of a values layer with its values.
+func ensureValuesLayer(ctx context.Context, namespace string, layer ValuesLayer) {
+	GinkgoHelper()
+
+	valuesKey := layer.ValuesKey
+	if valuesKey == "" {
+		valuesKey = "values.yaml"
+	}
+
+	var obj cr.Object
+	switch layer.Kind {
+	case "ConfigMap":
+		obj = &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: layer.Name, Namespace: namespace},
+			Data:       map[string]string{valuesKey: layer.Values},
+		}
+	case "Secret":
+		obj = &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: layer.Name, Namespace: namespace},
+			StringData: map[string]string{valuesKey: layer.Values},
+		}
+	default:
+		Fail(fmt.Sprintf("unsupported values layer kind '%s' for '%s', must be ConfigMap or Secret", layer.Kind, layer.Name))
+	}
+
+	logger.Log("Creating values layer %s %s/%s", layer.Kind, namespace, layer.Name)
+	err := state.GetFramework().MC().CreateOrUpdate(ctx, obj)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// DeleteHelmValuesLayers deletes the ConfigMaps and Secrets of the HelmRelease's ValuesFrom and ValuesOverrides
+// layers that were created by the framework (those with Values set). Not found errors are ignored.
+func DeleteHelmValuesLayers(ctx context.Context, cfg HelmReleaseConfig) error {
+	for _, layer := range valuesLayers(cfg) {
+		if layer.Values == "" {
+			continue
+		}
+
+		objectMeta := metav1.ObjectMeta{Name: layer.Name, Namespace: cfg.Namespace}
+		var obj cr.Object = &corev1.Secret{ObjectMeta: objectMeta}
+		if layer.Kind == "ConfigMap" {
+			obj = &corev1.ConfigMap{ObjectMeta: objectMeta}
+		}
+
+		err := state.GetFramework().MC().Delete(ctx, obj)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("deleting values layer %s %s/%s: %w", layer.Kind, cfg.Namespace, layer.Name, err)
+		}
+	}
+	return nil
+}
+
 // UpdateHelmReleaseVersion updates the chart version for an existing HelmRelease.
 // For HelmRepository sources, it updates spec.chart.spec.version on the HelmRelease.
-// For OCIRepository sources, it updates spec.ref.tag on the OCIRepository (sourced from cfg).
+// For OCIRepository sources, it updates spec.ref.tag on the OCIRepository (sourced from cfg), or spec.ref.digest
+// and spec.url if cfg.SourceDigest is set.
 func UpdateHelmReleaseVersion(ctx context.Context, cfg HelmReleaseConfig, version string) {
 	GinkgoHelper()
 
@@ -553,7 +838,7 @@ func UpdateHelmReleaseVersion(ctx context.Context, cfg HelmReleaseConfig, versio
 		if sourceNamespace == "" {
 			sourceNamespace = cfg.Namespace
 		}
-		updateOCIRepositoryTag(ctx, sourceName, sourceNamespace, version)
+		updateOCIRepositoryRef(ctx, sourceName, sourceNamespace, cfg, version)
 		return
 	}
 
@@ -0,0 +1,269 @@
+package client
+
+import (
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDependencyNotReadyMessage(t *testing.T) {
+	tests := []struct {
+		name            string
+		conditions      []metav1.Condition
+		expectedBlocked bool
+		expectedMessage string
+	}{
+		{
+			name:            "no conditions",
+			conditions:      nil,
+			expectedBlocked: false,
+		},
+		{
+			name: "blocked on dependency",
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: meta.DependencyNotReadyReason, Message: "dependency 'org-test/crds' is not ready"},
+			},
+			expectedBlocked: true,
+			expectedMessage: "dependency 'org-test/crds' is not ready",
+		},
+		{
+			name: "not ready for another reason",
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Reason: "InstallFailed", Message: "install failed"},
+			},
+			expectedBlocked: false,
+		},
+		{
+			name: "ready",
+			conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionTrue, Reason: "InstallSucceeded"},
+			},
+			expectedBlocked: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hr := &helmv2.HelmRelease{Status: helmv2.HelmReleaseStatus{Conditions: tc.conditions}}
+
+			message, blocked := dependencyNotReadyMessage(hr)
+			if blocked != tc.expectedBlocked {
+				t.Fatalf("Expected blocked to be %t but got %t", tc.expectedBlocked, blocked)
+			}
+			if message != tc.expectedMessage {
+				t.Fatalf("Expected message '%s' but got '%s'", tc.expectedMessage, message)
+			}
+		})
+	}
+}
+
+func TestBuildHelmReleaseDependsOn(t *testing.T) {
+	cfg := HelmReleaseConfig{
+		Name:      "my-app",
+		Namespace: "org-test",
+		ChartName: "my-app",
+		DependsOn: []helmv2.DependencyReference{{Name: "my-app-crds"}},
+	}
+
+	hr := buildHelmRelease(cfg)
+	if len(hr.Spec.DependsOn) != 1 || hr.Spec.DependsOn[0].Name != "my-app-crds" {
+		t.Fatalf("Expected dependsOn to contain 'my-app-crds' but got %v", hr.Spec.DependsOn)
+	}
+
+	cfg.DependsOn = nil
+	hr = buildHelmRelease(cfg)
+	if hr.Spec.DependsOn != nil {
+		t.Fatalf("Expected no dependsOn but got %v", hr.Spec.DependsOn)
+	}
+}
+
+func TestBuildHelmReleaseValuesFrom(t *testing.T) {
+	cfg := HelmReleaseConfig{
+		Name:      "my-app",
+		Namespace: "org-test",
+		ChartName: "my-app",
+		Values:    "replicas: 2",
+		ValuesFrom: []ValuesLayer{
+			{Kind: "ConfigMap", Name: "catalog-defaults"},
+			{Kind: "Secret", Name: "cluster-secrets", ValuesKey: "secret.yaml", TargetPath: "auth.token", Optional: true},
+		},
+	}
+
+	hr := buildHelmRelease(cfg)
+
+	expected := []helmv2.ValuesReference{
+		{Kind: "ConfigMap", Name: "catalog-defaults"},
+		{Kind: "Secret", Name: "cluster-secrets", ValuesKey: "secret.yaml", TargetPath: "auth.token", Optional: true},
+		{Kind: "Secret", Name: "my-app-values"},
+	}
+	if len(hr.Spec.ValuesFrom) != len(expected) {
+		t.Fatalf("Expected %d valuesFrom entries but got %d", len(expected), len(hr.Spec.ValuesFrom))
+	}
+	for i := range expected {
+		if hr.Spec.ValuesFrom[i] != expected[i] {
+			t.Fatalf("Expected valuesFrom[%d] to be %v but got %v", i, expected[i], hr.Spec.ValuesFrom[i])
+		}
+	}
+}
+
+func TestBuildHelmReleaseRemediationRetries(t *testing.T) {
+	retries, upgradeRetries := 3, 1
+	tests := []struct {
+		name                   string
+		cfg                    HelmReleaseConfig
+		expectedInstallRetries int
+		expectedUpgradeRetries int
+	}{
+		{
+			name:                   "defaults",
+			cfg:                    HelmReleaseConfig{Name: "my-app", ChartName: "my-app"},
+			expectedInstallRetries: 10,
+			expectedUpgradeRetries: 10,
+		},
+		{
+			name:                   "retries",
+			cfg:                    HelmReleaseConfig{Name: "my-app", ChartName: "my-app", Retries: &retries},
+			expectedInstallRetries: 3,
+			expectedUpgradeRetries: 3,
+		},
+		{
+			name:                   "upgrade retries",
+			cfg:                    HelmReleaseConfig{Name: "my-app", ChartName: "my-app", Retries: &retries, UpgradeRetries: &upgradeRetries},
+			expectedInstallRetries: 3,
+			expectedUpgradeRetries: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hr := buildHelmRelease(tc.cfg)
+			if hr.Spec.Install.Remediation.Retries != tc.expectedInstallRetries {
+				t.Fatalf("Expected %d install retries but got %d", tc.expectedInstallRetries, hr.Spec.Install.Remediation.Retries)
+			}
+			if hr.Spec.Upgrade.Remediation.Retries != tc.expectedUpgradeRetries {
+				t.Fatalf("Expected %d upgrade retries but got %d", tc.expectedUpgradeRetries, hr.Spec.Upgrade.Remediation.Retries)
+			}
+		})
+	}
+}
+
+func TestSetOCIRepositoryRefSwitchingSources(t *testing.T) {
+	localRegistry := "oci://localhost:5000/charts"
+	local := HelmReleaseConfig{
+		ChartName:      "hello-world",
+		SourceURL:      "oci://localhost:5000/charts/hello-world",
+		SourceDigest:   "sha256:abc123",
+		SourceInsecure: true,
+	}
+	published := HelmReleaseConfig{ChartName: "hello-world"}
+
+	steps := []struct {
+		name             string
+		cfg              HelmReleaseConfig
+		version          string
+		expectedURL      string
+		expectedTag      string
+		expectedDigest   string
+		expectedInsecure bool
+	}{
+		{
+			name:        "published version",
+			cfg:         published,
+			version:     "v1.2.1",
+			expectedURL: "oci://gsoci.azurecr.io/charts/giantswarm/hello-world",
+			expectedTag: "1.2.1",
+		},
+		{
+			name:             "local chart",
+			cfg:              local,
+			version:          "1.2.3-local.1",
+			expectedURL:      "oci://localhost:5000/charts/hello-world",
+			expectedTag:      "1.2.3-local.1",
+			expectedDigest:   "sha256:abc123",
+			expectedInsecure: true,
+		},
+		{
+			name:        "published version again",
+			cfg:         published,
+			version:     "v1.2.2",
+			expectedURL: "oci://gsoci.azurecr.io/charts/giantswarm/hello-world",
+			expectedTag: "1.2.2",
+		},
+		{
+			name:             "local chart again",
+			cfg:              local,
+			version:          "1.2.3-local.1",
+			expectedURL:      "oci://localhost:5000/charts/hello-world",
+			expectedTag:      "1.2.3-local.1",
+			expectedDigest:   "sha256:abc123",
+			expectedInsecure: true,
+		},
+	}
+
+	obj := &sourcev1beta2.OCIRepository{}
+	obj.Spec.URL = "oci://gsoci.azurecr.io/charts/giantswarm/hello-world"
+	for _, step := range steps {
+		setOCIRepositoryRef(obj, step.cfg, step.version, localRegistry)
+		assertOCIRepository(t, step.name, obj, step.expectedURL, step.expectedTag, step.expectedDigest, step.expectedInsecure)
+	}
+}
+
+func TestSetOCIRepositoryRefExistingSource(t *testing.T) {
+	tests := []struct {
+		name             string
+		localRegistry    string
+		cfg              HelmReleaseConfig
+		expectedURL      string
+		expectedInsecure bool
+	}{
+		{
+			name:             "custom URL is kept",
+			cfg:              HelmReleaseConfig{ChartName: "hello-world", SourceName: "existing"},
+			expectedURL:      "oci://registry.example.com/charts/hello-world",
+			expectedInsecure: true,
+		},
+		{
+			name:             "custom URL is kept with a local chart registry set",
+			localRegistry:    "oci://localhost:5000/charts",
+			cfg:              HelmReleaseConfig{ChartName: "hello-world", SourceName: "existing"},
+			expectedURL:      "oci://registry.example.com/charts/hello-world",
+			expectedInsecure: true,
+		},
+		{
+			name:        "source URL from the config",
+			cfg:         HelmReleaseConfig{ChartName: "hello-world", SourceName: "existing", SourceURL: "oci://other.example.com/charts/hello-world"},
+			expectedURL: "oci://other.example.com/charts/hello-world",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &sourcev1beta2.OCIRepository{}
+			obj.Spec.URL = "oci://registry.example.com/charts/hello-world"
+			obj.Spec.Insecure = true
+
+			setOCIRepositoryRef(obj, tc.cfg, "v1.2.3", tc.localRegistry)
+			assertOCIRepository(t, tc.name, obj, tc.expectedURL, "1.2.3", "", tc.expectedInsecure)
+		})
+	}
+}
+
+func assertOCIRepository(t *testing.T, name string, obj *sourcev1beta2.OCIRepository, url, tag, digest string, insecure bool) {
+	t.Helper()
+
+	if obj.Spec.URL != url {
+		t.Fatalf("%s: expected URL '%s' but got '%s'", name, url, obj.Spec.URL)
+	}
+	if obj.Spec.Insecure != insecure {
+		t.Fatalf("%s: expected insecure %t but got %t", name, insecure, obj.Spec.Insecure)
+	}
+	if obj.Spec.Reference.Tag != tag {
+		t.Fatalf("%s: expected tag '%s' but got '%s'", name, tag, obj.Spec.Reference.Tag)
+	}
+	if obj.Spec.Reference.Digest != digest {
+		t.Fatalf("%s: expected digest '%s' but got '%s'", name, digest, obj.Spec.Reference.Digest)
+	}
+}
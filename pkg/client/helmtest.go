@@ -0,0 +1,287 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/report"
+)
+
+// helmRelease is the subset of a Helm release, as stored by Helm in its storage Secrets, needed to run its tests
+type helmRelease struct {
+	Name      string     `json:"name"`
+	Namespace string     `json:"namespace"`
+	Version   int        `json:"version"`
+	Hooks     []helmHook `json:"hooks"`
+}
+
+// helmHook is a rendered hook of a Helm release
+type helmHook struct {
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"`
+	Manifest string   `json:"manifest"`
+	Events   []string `json:"events"`
+	Weight   int      `json:"weight"`
+}
+
+// RunHelmTests runs the test hooks (`helm.sh/hook: test`) of the latest deployed revision of a Helm release,
+// the same as `helm test` would, using the provided client of the cluster the release is installed in.
+// The logs of the test pods are written into the given directory (relative to the suite report directory)
+// and the test hooks are deleted once completed. An error listing the failed tests is returned if any fail.
+func RunHelmTests(ctx context.Context, c cr.Client, clientset kubernetes.Interface, releaseName, storageNamespace, reportDir string) error {
+	release, err := getDeployedHelmRelease(ctx, c, releaseName, storageNamespace)
+	if err != nil {
+		return err
+	}
+
+	hooks := testHooks(release)
+	if len(hooks) == 0 {
+		logger.Log("Helm release %s/%s has no test hooks", release.Namespace, release.Name)
+		return nil
+	}
+
+	created := []*unstructured.Unstructured{}
+	defer func() {
+		for _, obj := range created {
+			err := c.Delete(context.WithoutCancel(ctx), obj, cr.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !errors.IsNotFound(err) {
+				logger.Log("Failed to delete test hook %s %s: %v", obj.GetKind(), obj.GetName(), err)
+			}
+		}
+	}()
+
+	failed := []string{}
+	for _, hook := range hooks {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(hook.Manifest), &obj.Object); err != nil {
+			return fmt.Errorf("parsing test hook %s: %w", hook.Name, err)
+		}
+		if namespaced, err := c.IsObjectNamespaced(obj); err == nil && namespaced && obj.GetNamespace() == "" {
+			obj.SetNamespace(release.Namespace)
+		}
+
+		// Mirror Helm's default `before-hook-creation` delete policy
+		if err := deleteAndWait(ctx, c, obj); err != nil {
+			return err
+		}
+
+		logger.Log("Running Helm test hook %s %s", hook.Kind, hook.Name)
+		if err := c.Create(ctx, obj); err != nil {
+			return fmt.Errorf("creating test hook %s %s: %w", hook.Kind, hook.Name, err)
+		}
+		created = append(created, obj)
+
+		var succeeded bool
+		switch hook.Kind {
+		case "Pod":
+			succeeded, err = waitForTestPod(ctx, c, obj.GetName(), obj.GetNamespace())
+		case "Job":
+			succeeded, err = waitForTestJob(ctx, c, obj.GetName(), obj.GetNamespace())
+		default:
+			// Supporting resources of the test pods, such as ConfigMaps, don't need to be waited on
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("waiting for test hook %s %s: %w", hook.Kind, hook.Name, err)
+		}
+
+		if err := CollectHelmTestLogs(ctx, c, clientset, reportDir, obj.GetName(), obj.GetNamespace()); err != nil {
+			logger.Log("Failed to collect logs of test hook %s: %v", hook.Name, err)
+		}
+
+		if succeeded {
+			logger.Log("Helm test hook %s succeeded", hook.Name)
+		} else {
+			logger.Log("Helm test hook %s failed", hook.Name)
+			failed = append(failed, hook.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("helm tests of release %s/%s failed: %s", release.Namespace, release.Name, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// CollectHelmTestLogs writes the logs of the pod of a test hook into the given directory (relative to the suite
+// report directory). The hook can either be a Pod or a Job, in which case the logs of all its pods are collected.
+func CollectHelmTestLogs(ctx context.Context, c cr.Client, clientset kubernetes.Interface, dir, name, namespace string) error {
+	pods := []corev1.Pod{}
+
+	pod := &corev1.Pod{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, pod)
+	if err == nil {
+		pods = append(pods, *pod)
+	} else if errors.IsNotFound(err) {
+		jobPods := &corev1.PodList{}
+		err = c.List(ctx, jobPods, cr.InNamespace(namespace), cr.MatchingLabels{"job-name": name})
+		if err != nil {
+			return err
+		}
+		pods = append(pods, jobPods.Items...)
+	} else {
+		return err
+	}
+
+	for _, pod := range pods {
+		if err := report.CollectPodLogs(ctx, clientset, dir, pod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getDeployedHelmRelease returns the latest deployed revision of a Helm release from its storage Secrets.
+func getDeployedHelmRelease(ctx context.Context, c cr.Client, releaseName, storageNamespace string) (*helmRelease, error) {
+	secrets := &corev1.SecretList{}
+	err := c.List(ctx, secrets, cr.InNamespace(storageNamespace), cr.MatchingLabels{"owner": "helm", "name": releaseName, "status": "deployed"})
+	if err != nil {
+		return nil, fmt.Errorf("listing Helm release Secrets of %s/%s: %w", storageNamespace, releaseName, err)
+	}
+
+	var latest *corev1.Secret
+	latestVersion := -1
+	for i := range secrets.Items {
+		version, err := strconv.Atoi(secrets.Items[i].Labels["version"])
+		if err == nil && version > latestVersion {
+			latest = &secrets.Items[i]
+			latestVersion = version
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no deployed revision of Helm release %s/%s found", storageNamespace, releaseName)
+	}
+
+	return decodeHelmRelease(latest.Data["release"])
+}
+
+// decodeHelmRelease decodes a Helm release as stored in the `release` key of Helm's storage Secrets,
+// which is base64 encoded and usually gzipped JSON.
+func decodeHelmRelease(data []byte) (*helmRelease, error) {
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding Helm release: %w", err)
+	}
+
+	if bytes.HasPrefix(decoded, []byte{0x1f, 0x8b, 0x08}) {
+		reader, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing Helm release: %w", err)
+		}
+		decoded, err = io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decompressing Helm release: %w", err)
+		}
+	}
+
+	release := &helmRelease{}
+	if err := json.Unmarshal(decoded, release); err != nil {
+		return nil, fmt.Errorf("unmarshalling Helm release: %w", err)
+	}
+	return release, nil
+}
+
+// testHooks returns the test hooks of the release in the order Helm runs them (by weight, then name)
+func testHooks(release *helmRelease) []helmHook {
+	hooks := []helmHook{}
+	for _, hook := range release.Hooks {
+		for _, event := range hook.Events {
+			// `test-success` is the deprecated Helm 2 name of the test event
+			if event == "test" || event == "test-success" {
+				hooks = append(hooks, hook)
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(hooks, func(i, j int) bool {
+		if hooks[i].Weight != hooks[j].Weight {
+			return hooks[i].Weight < hooks[j].Weight
+		}
+		return hooks[i].Name < hooks[j].Name
+	})
+	return hooks
+}
+
+// deleteAndWait deletes the given object, if it exists, and waits for it to be gone
+func deleteAndWait(ctx context.Context, c cr.Client, obj *unstructured.Unstructured) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	key := cr.ObjectKeyFromObject(obj)
+
+	err := c.Delete(ctx, obj.DeepCopy(), cr.PropagationPolicy(metav1.DeletePropagationBackground))
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("deleting previous %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+
+	return wait.PollUntilContextTimeout(ctx, 2*time.Second, 2*time.Minute, true, func(ctx context.Context) (bool, error) {
+		err := c.Get(ctx, key, existing)
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// waitForTestPod waits for the test pod to complete, returning if it succeeded
+func waitForTestPod(ctx context.Context, c cr.Client, name, namespace string) (bool, error) {
+	var phase corev1.PodPhase
+	err := wait.PollUntilContextCancel(ctx, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		pod := &corev1.Pod{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, pod); err != nil {
+			return false, err
+		}
+		phase = pod.Status.Phase
+		return phase == corev1.PodSucceeded || phase == corev1.PodFailed, nil
+	})
+	return phase == corev1.PodSucceeded, err
+}
+
+// waitForTestJob waits for the test job to complete, returning if it succeeded
+func waitForTestJob(ctx context.Context, c cr.Client, name, namespace string) (bool, error) {
+	succeeded := false
+	err := wait.PollUntilContextCancel(ctx, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		job := &batchv1.Job{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, job); err != nil {
+			return false, err
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				succeeded = true
+				return true, nil
+			case batchv1.JobFailed:
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	return succeeded, err
+}
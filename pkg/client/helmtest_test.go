@@ -0,0 +1,59 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+)
+
+func TestDecodeHelmRelease(t *testing.T) {
+	releaseJSON := `{"name":"my-app","namespace":"default","version":3,"hooks":[` +
+		`{"name":"my-app-test-b","kind":"Pod","events":["test"],"weight":0,"manifest":"kind: Pod"},` +
+		`{"name":"my-app-pre-install","kind":"Job","events":["pre-install"],"weight":-5},` +
+		`{"name":"my-app-test-config","kind":"ConfigMap","events":["test"],"weight":-1},` +
+		`{"name":"my-app-test-a","kind":"Pod","events":["test-success"],"weight":0}` +
+		`]}`
+
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, _ = writer.Write([]byte(releaseJSON))
+	_ = writer.Close()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "gzipped release",
+			data: []byte(base64.StdEncoding.EncodeToString(gzipped.Bytes())),
+		},
+		{
+			name: "uncompressed release",
+			data: []byte(base64.StdEncoding.EncodeToString([]byte(releaseJSON))),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			release, err := decodeHelmRelease(tc.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if release.Name != "my-app" || release.Namespace != "default" || release.Version != 3 {
+				t.Fatalf("Unexpected release details: %+v", release)
+			}
+
+			hooks := testHooks(release)
+			expected := []string{"my-app-test-config", "my-app-test-a", "my-app-test-b"}
+			if len(hooks) != len(expected) {
+				t.Fatalf("Expected %d test hooks but got %d", len(expected), len(hooks))
+			}
+			for i, name := range expected {
+				if hooks[i].Name != name {
+					t.Fatalf("Expected test hook %d to be '%s' but got '%s'", i, name, hooks[i].Name)
+				}
+			}
+		})
+	}
+}
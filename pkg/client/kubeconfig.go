@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+)
+
+// GetWCKubeConfig returns the kubeconfig of the given workload cluster as stored in the
+// `{clusterName}-kubeconfig` Secret on the MC.
+func GetWCKubeConfig(ctx context.Context, clusterName, namespace string) ([]byte, error) {
+	secretName := fmt.Sprintf("%s-kubeconfig", clusterName)
+
+	secret := &corev1.Secret{}
+	err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: secretName, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("getting kubeconfig Secret %s/%s: %w", namespace, secretName, err)
+	}
+
+	kubeconfig, ok := secret.Data["value"]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig Secret %s/%s has no `value` key", namespace, secretName)
+	}
+	return kubeconfig, nil
+}
+
+// GetWCClientset returns a Kubernetes clientset for the given workload cluster.
+// This is useful for API calls not supported by the controller-runtime client, such as fetching pod logs.
+func GetWCClientset(ctx context.Context, clusterName, namespace string) (kubernetes.Interface, error) {
+	kubeconfig, err := GetWCKubeConfig(ctx, clusterName, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig of %s: %w", clusterName, err)
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// GetMCClientset returns a Kubernetes clientset for the MC using `E2E_KUBECONFIG` and `E2E_KUBECONFIG_CONTEXT`.
+// This is useful for API calls not supported by the controller-runtime client, such as fetching pod logs.
+func GetMCClientset() (kubernetes.Interface, error) {
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: os.Getenv("E2E_KUBECONFIG")},
+		&clientcmd.ConfigOverrides{CurrentContext: os.Getenv("E2E_KUBECONFIG_CONTEXT")},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading MC kubeconfig: %w", err)
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
@@ -0,0 +1,463 @@
+package client
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// EnvLocalChartRegistry is the env var containing the OCI registry local charts are pushed to,
+	// e.g. `oci://registry.example.com/charts`.
+	EnvLocalChartRegistry = "E2E_LOCAL_CHART_REGISTRY"
+
+	helmConfigMediaType = "application/vnd.cncf.helm.config.v1+json"
+	helmChartMediaType  = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	ociManifestType     = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// LocalChart is a chart packaged from a local directory and pushed to an OCI registry.
+type LocalChart struct {
+	// Name is the name of the chart from its Chart.yaml.
+	Name string
+	// Version is the version the chart was packaged with.
+	Version string
+	// URL is the OCI URL of the chart repository, e.g. `oci://registry.example.com/charts/my-app`.
+	URL string
+	// Digest is the digest of the pushed chart manifest.
+	Digest string
+	// Insecure is true if the registry is accessed over plain HTTP.
+	Insecure bool
+}
+
+// PushLocalChart packages the chart found in chartDir and pushes it to the OCI registry set in
+// E2E_LOCAL_CHART_REGISTRY. If version is empty the chart version is set to the version from the
+// Chart.yaml suffixed with a unique `-local.{timestamp}` pre-release.
+// Registry credentials are taken from E2E_HELM_REGISTRY_USERNAME / E2E_HELM_REGISTRY_PASSWORD if set.
+func PushLocalChart(ctx context.Context, chartDir, version string) (*LocalChart, error) {
+	registry := os.Getenv(EnvLocalChartRegistry)
+	if registry == "" {
+		return nil, fmt.Errorf("`%s` must be set to the OCI registry to push local charts to", EnvLocalChartRegistry)
+	}
+	return PushChart(ctx, registry, chartDir, version)
+}
+
+// PushChart packages the chart found in chartDir and pushes it to the given OCI registry
+// (e.g. `oci://registry.example.com/charts`). See PushLocalChart for details.
+func PushChart(ctx context.Context, registry, chartDir, version string) (*LocalChart, error) {
+	if version == "" {
+		metadata, err := readChartMetadata(chartDir)
+		if err != nil {
+			return nil, err
+		}
+		version = fmt.Sprintf("%s-local.%s", metadata["version"], time.Now().UTC().Format("20060102150405"))
+	}
+
+	name, config, archive, err := PackageChart(chartDir, version)
+	if err != nil {
+		return nil, err
+	}
+
+	host, repoPath, plainHTTP := parseRegistry(registry)
+	repository := strings.TrimPrefix(path.Join(repoPath, name), "/")
+
+	pusher := &registryClient{
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+		baseURL:    fmt.Sprintf("https://%s", host),
+		username:   os.Getenv(EnvRegistryUsername),
+		password:   os.Getenv(EnvRegistryPassword),
+	}
+	if plainHTTP {
+		pusher.baseURL = fmt.Sprintf("http://%s", host)
+	}
+
+	logger.Log("Pushing local chart %s (version: %s) to %s/%s", name, version, host, repository)
+
+	configDescriptor, err := pusher.pushBlob(ctx, repository, config, helmConfigMediaType)
+	if err != nil {
+		return nil, err
+	}
+	chartDescriptor, err := pusher.pushBlob(ctx, repository, archive, helmChartMediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     ociManifestType,
+		"config":        configDescriptor,
+		"layers":        []ociDescriptor{chartDescriptor},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// OCI tags don't support `+` so Helm replaces it with `_`
+	tag := strings.ReplaceAll(version, "+", "_")
+	digest, err := pusher.pushManifest(ctx, repository, tag, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log("Pushed local chart %s to %s/%s@%s", name, host, repository, digest)
+	return &LocalChart{
+		Name:     name,
+		Version:  version,
+		URL:      fmt.Sprintf("oci://%s/%s", host, repository),
+		Digest:   digest,
+		Insecure: plainHTTP,
+	}, nil
+}
+
+// PackageChart packages the chart found in chartDir the same way `helm package` does, returning the chart name,
+// the Helm OCI config (the Chart.yaml as JSON) and the gzipped tar archive. If version is set it replaces the
+// version in the packaged Chart.yaml. Files matching the patterns in `.helmignore` are excluded.
+func PackageChart(chartDir, version string) (string, []byte, []byte, error) {
+	metadata, err := readChartMetadata(chartDir)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	name, _ := metadata["name"].(string)
+	if name == "" {
+		return "", nil, nil, fmt.Errorf("chart in %s has no name", chartDir)
+	}
+	if version != "" {
+		metadata["version"] = version
+	}
+
+	chartYAML, err := yaml.Marshal(metadata)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	config, err := json.Marshal(metadata)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	ignorePatterns, err := readHelmIgnore(chartDir)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err = filepath.WalkDir(chartDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(chartDir, filePath)
+		if err != nil || relPath == "." {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if isHelmIgnored(ignorePatterns, relPath, entry.IsDir()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(filePath) // #nosec G304
+		if err != nil {
+			return err
+		}
+		if relPath == "Chart.yaml" {
+			content = chartYAML
+		}
+
+		err = tarWriter.WriteHeader(&tar.Header{
+			Name:    path.Join(name, relPath),
+			Mode:    0o644,
+			Size:    int64(len(content)),
+			ModTime: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(content)
+		return err
+	})
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("packaging chart %s: %w", chartDir, err)
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return "", nil, nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return "", nil, nil, err
+	}
+
+	return name, config, buf.Bytes(), nil
+}
+
+// readChartMetadata reads the Chart.yaml of the chart in chartDir
+func readChartMetadata(chartDir string) (map[string]any, error) {
+	content, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml")) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading Chart.yaml: %w", err)
+	}
+	metadata := map[string]any{}
+	if err := yaml.Unmarshal(content, &metadata); err != nil {
+		return nil, fmt.Errorf("parsing Chart.yaml: %w", err)
+	}
+	return metadata, nil
+}
+
+// readHelmIgnore returns the patterns in the chart's `.helmignore` file, if it exists
+func readHelmIgnore(chartDir string) ([]string, error) {
+	file, err := os.Open(filepath.Join(chartDir, ".helmignore")) // #nosec G304
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint:errcheck
+
+	patterns := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// isHelmIgnored returns if the given path, relative to the chart directory, matches any of the `.helmignore` patterns.
+// Patterns are matched against both the full relative path and the base name, a trailing `/` only matches directories.
+// The Chart.yaml is never ignored.
+func isHelmIgnored(patterns []string, relPath string, isDir bool) bool {
+	if relPath == "Chart.yaml" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			if !isDir {
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		pattern = strings.TrimPrefix(pattern, "/")
+
+		if matched, _ := path.Match(pattern, relPath); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(relPath)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRegistry splits an OCI registry reference into the host and repository path. Registries given with an
+// `http://` scheme or on localhost are accessed over plain HTTP.
+func parseRegistry(registry string) (string, string, bool) {
+	plainHTTP := strings.HasPrefix(registry, "http://")
+	for _, prefix := range []string{"oci://", "https://", "http://"} {
+		registry = strings.TrimPrefix(registry, prefix)
+	}
+	registry = strings.TrimSuffix(registry, "/")
+
+	host, repoPath, _ := strings.Cut(registry, "/")
+	hostname := strings.Split(host, ":")[0]
+	if hostname == "localhost" || hostname == "127.0.0.1" {
+		plainHTTP = true
+	}
+	return host, repoPath, plainHTTP
+}
+
+// ociDescriptor describes a blob pushed to an OCI registry
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int    `json:"size"`
+}
+
+// registryClient is a minimal client of the OCI distribution API used to push charts
+type registryClient struct {
+	httpClient *http.Client
+	baseURL    string
+	username   string
+	password   string
+	token      string
+}
+
+// pushBlob uploads the given content as a blob unless it already exists in the repository
+func (r *registryClient) pushBlob(ctx context.Context, repository string, content []byte, mediaType string) (ociDescriptor, error) {
+	descriptor := ociDescriptor{
+		MediaType: mediaType,
+		Digest:    fmt.Sprintf("sha256:%x", sha256.Sum256(content)),
+		Size:      len(content),
+	}
+
+	resp, err := r.do(ctx, http.MethodHead, fmt.Sprintf("%s/v2/%s/blobs/%s", r.baseURL, repository, descriptor.Digest), nil, "")
+	if err != nil {
+		return descriptor, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return descriptor, nil
+	}
+
+	resp, err = r.do(ctx, http.MethodPost, fmt.Sprintf("%s/v2/%s/blobs/uploads/", r.baseURL, repository), nil, "")
+	if err != nil {
+		return descriptor, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return descriptor, fmt.Errorf("starting blob upload to %s: unexpected status %s", repository, resp.Status)
+	}
+
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return descriptor, fmt.Errorf("parsing blob upload location: %w", err)
+	}
+	query := location.Query()
+	query.Set("digest", descriptor.Digest)
+	location.RawQuery = query.Encode()
+
+	resp, err = r.do(ctx, http.MethodPut, location.String(), content, "application/octet-stream")
+	if err != nil {
+		return descriptor, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return descriptor, fmt.Errorf("uploading blob to %s: unexpected status %s", repository, resp.Status)
+	}
+	return descriptor, nil
+}
+
+// pushManifest uploads the manifest with the given tag and returns its digest
+func (r *registryClient) pushManifest(ctx context.Context, repository, tag string, manifest []byte) (string, error) {
+	resp, err := r.do(ctx, http.MethodPut, fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL, repository, tag), manifest, ociManifestType)
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("uploading manifest to %s: unexpected status %s", repository, resp.Status)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+	}
+	return digest, nil
+}
+
+// do performs the request, authenticating with a bearer token or basic auth if challenged by the registry
+func (r *registryClient) do(ctx context.Context, method, requestURL string, body []byte, contentType string) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if r.token != "" {
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		} else if r.username != "" {
+			req.SetBasicAuth(r.username, r.password)
+		}
+		return r.httpClient.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	_ = resp.Body.Close()
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return nil, fmt.Errorf("%s %s: unauthorized, check `%s` / `%s`", method, requestURL, EnvRegistryUsername, EnvRegistryPassword)
+	}
+	if err := r.fetchToken(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+// fetchToken requests a bearer token from the auth server given in the registry's challenge
+func (r *registryClient) fetchToken(ctx context.Context, challenge string) error {
+	params := map[string]string{}
+	for _, part := range strings.Split(challenge[len("bearer "):], ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if found {
+			params[key] = strings.Trim(value, `"`)
+		}
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("invalid registry auth challenge: %s", challenge)
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching registry token: unexpected status %s", resp.Status)
+	}
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, &token); err != nil {
+		return fmt.Errorf("parsing registry token: %w", err)
+	}
+
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeTestChart(t *testing.T) string {
+	t.Helper()
+
+	chartDir := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":             "apiVersion: v2\nname: hello-world\nversion: 1.2.3\n",
+		"values.yaml":            "replicas: 1\n",
+		"templates/service.yaml": "kind: Service\n",
+		"ci/test-values.yaml":    "replicas: 2\n",
+		"README.md.bak":          "backup\n",
+		".helmignore":            "# ignored files\nci/\n*.bak\n",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(chartDir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			t.Fatalf("Failed to create chart dir: %v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write chart file: %v", err)
+		}
+	}
+	return chartDir
+}
+
+func TestPackageChart(t *testing.T) {
+	chartDir := writeTestChart(t)
+
+	name, config, archive, err := PackageChart(chartDir, "1.2.4-local.1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if name != "hello-world" {
+		t.Fatalf("Expected chart name 'hello-world' but got '%s'", name)
+	}
+
+	metadata := map[string]any{}
+	if err := json.Unmarshal(config, &metadata); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if metadata["version"] != "1.2.4-local.1" {
+		t.Fatalf("Expected config version '1.2.4-local.1' but got '%v'", metadata["version"])
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	files := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		content, _ := io.ReadAll(tarReader)
+		files[header.Name] = string(content)
+	}
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expected := []string{"hello-world/.helmignore", "hello-world/Chart.yaml", "hello-world/templates/service.yaml", "hello-world/values.yaml"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected files %v but got %v", expected, names)
+	}
+	if !strings.Contains(files["hello-world/Chart.yaml"], "version: 1.2.4-local.1") {
+		t.Fatalf("Expected packaged Chart.yaml to have the new version but got:\n%s", files["hello-world/Chart.yaml"])
+	}
+}
+
+func TestParseRegistry(t *testing.T) {
+	tests := []struct {
+		registry          string
+		expectedHost      string
+		expectedPath      string
+		expectedPlainHTTP bool
+	}{
+		{registry: "oci://gsoci.azurecr.io/charts/giantswarm", expectedHost: "gsoci.azurecr.io", expectedPath: "charts/giantswarm"},
+		{registry: "oci://localhost:5000/", expectedHost: "localhost:5000", expectedPath: "", expectedPlainHTTP: true},
+		{registry: "http://registry.local/charts", expectedHost: "registry.local", expectedPath: "charts", expectedPlainHTTP: true},
+		{registry: "127.0.0.1:5000/charts", expectedHost: "127.0.0.1:5000", expectedPath: "charts", expectedPlainHTTP: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.registry, func(t *testing.T) {
+			host, repoPath, plainHTTP := parseRegistry(tc.registry)
+			if host != tc.expectedHost || repoPath != tc.expectedPath || plainHTTP != tc.expectedPlainHTTP {
+				t.Fatalf("Expected (%s, %s, %t) but got (%s, %s, %t)", tc.expectedHost, tc.expectedPath, tc.expectedPlainHTTP, host, repoPath, plainHTTP)
+			}
+		})
+	}
+}
+
+// testRegistry is a minimal in-memory OCI registry supporting blob and manifest uploads
+type testRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+}
+
+func (r *testRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	switch {
+	case req.Method == http.MethodHead && strings.Contains(req.URL.Path, "/blobs/"):
+		digest := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+		if _, ok := r.blobs[digest]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/blobs/uploads/"):
+		w.Header().Set("Location", req.URL.Path+"upload-id")
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == http.MethodPut && strings.Contains(req.URL.Path, "/blobs/uploads/"):
+		digest := req.URL.Query().Get("digest")
+		if digest != fmt.Sprintf("sha256:%x", sha256.Sum256(body)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.blobs[digest] = body
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == http.MethodPut && strings.Contains(req.URL.Path, "/manifests/"):
+		r.manifests[req.URL.Path] = body
+		w.Header().Set("Docker-Content-Digest", fmt.Sprintf("sha256:%x", sha256.Sum256(body)))
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPushChart(t *testing.T) {
+	registry := &testRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	chart, err := PushChart(context.Background(), "oci://"+host+"/charts", writeTestChart(t), "1.2.4+build.1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if chart.URL != "oci://"+host+"/charts/hello-world" {
+		t.Fatalf("Unexpected chart URL '%s'", chart.URL)
+	}
+	if !chart.Insecure {
+		t.Fatalf("Expected local registry to be insecure")
+	}
+	if len(registry.blobs) != 2 {
+		t.Fatalf("Expected config and chart blobs to be pushed but got %d blobs", len(registry.blobs))
+	}
+
+	manifest, ok := registry.manifests["/v2/charts/hello-world/manifests/1.2.4_build.1"]
+	if !ok {
+		t.Fatalf("Expected manifest to be pushed with tag '1.2.4_build.1'")
+	}
+	if chart.Digest != fmt.Sprintf("sha256:%x", sha256.Sum256(manifest)) {
+		t.Fatalf("Unexpected chart digest '%s'", chart.Digest)
+	}
+
+	parsed := struct {
+		Config ociDescriptor   `json:"config"`
+		Layers []ociDescriptor `json:"layers"`
+	}{}
+	if err := json.Unmarshal(manifest, &parsed); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	if parsed.Config.MediaType != helmConfigMediaType || len(parsed.Layers) != 1 || parsed.Layers[0].MediaType != helmChartMediaType {
+		t.Fatalf("Unexpected manifest: %s", manifest)
+	}
+	if _, ok := registry.blobs[parsed.Layers[0].Digest]; !ok {
+		t.Fatalf("Expected chart layer '%s' to have been pushed", parsed.Layers[0].Digest)
+	}
+}
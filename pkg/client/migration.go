@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/clustertest/v5/pkg/application"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+)
+
+const (
+	// AppOperatorPausedAnnotation stops app-operator from reconciling an App CR.
+	AppOperatorPausedAnnotation = "app-operator.giantswarm.io/paused"
+	// ChartOperatorPausedAnnotation stops chart-operator from reconciling a Chart CR.
+	ChartOperatorPausedAnnotation = "chart-operator.giantswarm.io/paused"
+)
+
+// DeleteAppKeepingRelease deletes the App CR, and the Chart CR created for it, without uninstalling the Helm release,
+// e.g. to hand the release over to a Flux HelmRelease. Both CRs are paused and their finalizers removed before they're
+// deleted so neither app-operator nor chart-operator uninstalls the chart. Waits until both CRs are gone.
+// Timeout can be controlled via the provided context.
+func DeleteAppKeepingRelease(ctx context.Context, app *application.Application) error {
+	appCR := &v1alpha1.App{}
+	err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: app.InstallName, Namespace: app.GetNamespace()}, appCR)
+	if err != nil {
+		return err
+	}
+
+	// Pause the App first so app-operator doesn't recreate the Chart CR
+	logger.Log("Pausing App %s/%s", appCR.Namespace, appCR.Name)
+	if err := pauseAndRemoveFinalizers(ctx, state.GetFramework().MC(), appCR, AppOperatorPausedAnnotation); err != nil {
+		return fmt.Errorf("pausing App %s/%s: %w", appCR.Namespace, appCR.Name, err)
+	}
+
+	chartClient := cr.Client(state.GetFramework().MC())
+	if !app.InCluster && app.ClusterName != "" {
+		wcClient, err := state.GetFramework().WC(app.ClusterName)
+		if err != nil {
+			return err
+		}
+		chartClient = wcClient
+	}
+	chart := &v1alpha1.Chart{}
+	chartName := strings.TrimPrefix(app.InstallName, fmt.Sprintf("%s-", app.ClusterName))
+	err = chartClient.Get(ctx, types.NamespacedName{Name: chartName, Namespace: "giantswarm"}, chart)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		logger.Log("Deleting Chart %s/%s without uninstalling the release", chart.Namespace, chart.Name)
+		if err := pauseAndRemoveFinalizers(ctx, chartClient, chart, ChartOperatorPausedAnnotation); err != nil {
+			return fmt.Errorf("pausing Chart %s/%s: %w", chart.Namespace, chart.Name, err)
+		}
+		if err := deleteAndWaitForRemoval(ctx, chartClient, chart); err != nil {
+			return err
+		}
+	}
+
+	logger.Log("Deleting App %s/%s without uninstalling the release", appCR.Namespace, appCR.Name)
+	return deleteAndWaitForRemoval(ctx, state.GetFramework().MC(), appCR)
+}
+
+// GetHelmReleaseStorageRevision returns the revision of the latest deployed release of the Helm release, as found in
+// Helm's release storage Secrets in the storage namespace of the cluster the provided client points at.
+func GetHelmReleaseStorageRevision(ctx context.Context, c cr.Client, releaseName, storageNamespace string) (int, error) {
+	release, err := getDeployedHelmRelease(ctx, c, releaseName, storageNamespace)
+	if err != nil {
+		return 0, err
+	}
+	return release.Version, nil
+}
+
+// pauseAndRemoveFinalizers sets the paused annotation on the object and removes its finalizers
+func pauseAndRemoveFinalizers(ctx context.Context, c cr.Client, obj cr.Object, annotation string) error {
+	patch := cr.MergeFrom(obj.DeepCopyObject().(cr.Object))
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[annotation] = "true"
+	obj.SetAnnotations(annotations)
+	obj.SetFinalizers(nil)
+
+	return c.Patch(ctx, obj, patch)
+}
+
+// deleteAndWaitForRemoval deletes the object and waits until it's gone
+func deleteAndWaitForRemoval(ctx context.Context, c cr.Client, obj cr.Object) error {
+	if err := c.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	key := cr.ObjectKeyFromObject(obj)
+	return wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		err := c.Get(ctx, key, obj)
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+)
+
+// ReconcileOptions configures how ReconcileHelmReleaseNow requests a reconciliation,
+// mirroring the flags of `flux reconcile helmrelease`.
+type ReconcileOptions struct {
+	// Force forces a one-off Helm install or upgrade, even if nothing changed (`reconcile.fluxcd.io/forceAt`).
+	Force bool
+	// Reset resets the install / upgrade failure counts so remediation retries start again (`reconcile.fluxcd.io/resetAt`).
+	Reset bool
+	// WithSource reconciles the source of the chart (the OCIRepository or generated HelmChart) first,
+	// e.g. after pushing a new chart with the same tag.
+	WithSource bool
+}
+
+// ReconcileHelmReleaseNow requests an immediate reconciliation of the HelmRelease, instead of waiting for its
+// interval, and waits until helm-controller has handled the request (`status.lastHandledReconcileAt`).
+// Timeout can be controlled via the provided context. The wait is also aborted if the suite context is cancelled.
+func ReconcileHelmReleaseNow(ctx context.Context, name, namespace string, opts ReconcileOptions) error {
+	ctx, cancel := withSuiteContext(ctx)
+	defer cancel()
+
+	c := state.GetFramework().MC()
+
+	hr := &helmv2.HelmRelease{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, hr); err != nil {
+		return err
+	}
+
+	if opts.WithSource {
+		if err := reconcileHelmReleaseSource(ctx, c, hr); err != nil {
+			return err
+		}
+	}
+
+	token := newReconcileToken()
+	annotations := map[string]string{meta.ReconcileRequestAnnotation: token}
+	if opts.Force {
+		annotations[helmv2.ForceRequestAnnotation] = token
+	}
+	if opts.Reset {
+		annotations[helmv2.ResetRequestAnnotation] = token
+	}
+
+	logger.Log("Requesting reconciliation of HelmRelease %s/%s (force: %t, reset: %t)", namespace, name, opts.Force, opts.Reset)
+	if err := patchHelmRelease(ctx, c, hr, annotations, nil); err != nil {
+		return err
+	}
+	return waitForHelmReleaseReconcile(ctx, c, hr, token)
+}
+
+// SuspendHelmRelease suspends the reconciliation of the HelmRelease (spec.suspend), e.g. to simulate a paused
+// release, and waits until helm-controller has observed it.
+// Timeout can be controlled via the provided context. The wait is also aborted if the suite context is cancelled.
+func SuspendHelmRelease(ctx context.Context, name, namespace string) error {
+	return setHelmReleaseSuspended(ctx, name, namespace, true)
+}
+
+// ResumeHelmRelease resumes the reconciliation of a suspended HelmRelease and waits until helm-controller has
+// reconciled it again. It doesn't wait for the HelmRelease to become ready.
+// Timeout can be controlled via the provided context. The wait is also aborted if the suite context is cancelled.
+func ResumeHelmRelease(ctx context.Context, name, namespace string) error {
+	return setHelmReleaseSuspended(ctx, name, namespace, false)
+}
+
+// setHelmReleaseSuspended sets spec.suspend, along with a reconcile request so it can be told when the change has
+// been handled by helm-controller
+func setHelmReleaseSuspended(ctx context.Context, name, namespace string, suspend bool) error {
+	ctx, cancel := withSuiteContext(ctx)
+	defer cancel()
+
+	c := state.GetFramework().MC()
+
+	hr := &helmv2.HelmRelease{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, hr); err != nil {
+		return err
+	}
+
+	token := newReconcileToken()
+	if suspend {
+		logger.Log("Suspending HelmRelease %s/%s", namespace, name)
+	} else {
+		logger.Log("Resuming HelmRelease %s/%s", namespace, name)
+	}
+	err := patchHelmRelease(ctx, c, hr, map[string]string{meta.ReconcileRequestAnnotation: token}, func(hr *helmv2.HelmRelease) {
+		hr.Spec.Suspend = suspend
+	})
+	if err != nil {
+		return err
+	}
+	return waitForHelmReleaseReconcile(ctx, c, hr, token)
+}
+
+// patchHelmRelease merge patches the given annotations, and any spec changes made by mutate, onto the HelmRelease
+func patchHelmRelease(ctx context.Context, c cr.Client, hr *helmv2.HelmRelease, annotations map[string]string, mutate func(*helmv2.HelmRelease)) error {
+	patch := cr.MergeFrom(hr.DeepCopy())
+
+	hrAnnotations := hr.GetAnnotations()
+	if hrAnnotations == nil {
+		hrAnnotations = map[string]string{}
+	}
+	for key, value := range annotations {
+		hrAnnotations[key] = value
+	}
+	hr.SetAnnotations(hrAnnotations)
+	if mutate != nil {
+		mutate(hr)
+	}
+
+	if err := c.Patch(ctx, hr, patch); err != nil {
+		return fmt.Errorf("patching HelmRelease %s/%s: %w", hr.Namespace, hr.Name, err)
+	}
+	return nil
+}
+
+// waitForHelmReleaseReconcile waits until helm-controller has handled the reconcile request with the given token,
+// including any force or reset request made with it
+func waitForHelmReleaseReconcile(ctx context.Context, c cr.Client, hr *helmv2.HelmRelease, token string) error {
+	force := hr.GetAnnotations()[helmv2.ForceRequestAnnotation] == token
+	reset := hr.GetAnnotations()[helmv2.ResetRequestAnnotation] == token
+
+	key := cr.ObjectKeyFromObject(hr)
+	err := wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		current := &helmv2.HelmRelease{}
+		if err := c.Get(ctx, key, current); err != nil {
+			return false, err
+		}
+		if current.Status.LastHandledReconcileAt != token {
+			return false, nil
+		}
+		if force && current.Status.LastHandledForceAt != token {
+			return false, nil
+		}
+		if reset && current.Status.LastHandledResetAt != token {
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for HelmRelease %s/%s to handle the reconcile request: %w", hr.Namespace, hr.Name, err)
+	}
+
+	logger.Log("HelmRelease %s/%s has handled the reconcile request", hr.Namespace, hr.Name)
+	return nil
+}
+
+// reconcileHelmReleaseSource requests the reconciliation of the source of the HelmRelease's chart and waits until
+// source-controller has handled it
+func reconcileHelmReleaseSource(ctx context.Context, c cr.Client, hr *helmv2.HelmRelease) error {
+	_ = sourcev1.AddToScheme(c.Scheme())
+	_ = sourcev1beta2.AddToScheme(c.Scheme())
+
+	var kind string
+	var source cr.Object
+	var lastHandledReconcileAt func() string
+	switch {
+	case hr.Spec.ChartRef != nil && hr.Spec.ChartRef.Kind == string(SourceKindOCIRepository):
+		namespace := hr.Spec.ChartRef.Namespace
+		if namespace == "" {
+			namespace = hr.Namespace
+		}
+		repo := &sourcev1beta2.OCIRepository{}
+		repo.SetName(hr.Spec.ChartRef.Name)
+		repo.SetNamespace(namespace)
+		kind = string(SourceKindOCIRepository)
+		source = repo
+		lastHandledReconcileAt = func() string { return repo.Status.LastHandledReconcileAt }
+	case hr.Status.HelmChart != "":
+		namespace, name := hr.Status.GetHelmChart()
+		chart := &sourcev1.HelmChart{}
+		chart.SetName(name)
+		chart.SetNamespace(namespace)
+		kind = sourcev1.HelmChartKind
+		source = chart
+		lastHandledReconcileAt = func() string { return chart.Status.LastHandledReconcileAt }
+	default:
+		return nil
+	}
+
+	key := cr.ObjectKeyFromObject(source)
+	if err := c.Get(ctx, key, source); err != nil {
+		return err
+	}
+
+	token := newReconcileToken()
+	patch := cr.MergeFrom(source.DeepCopyObject().(cr.Object))
+	annotations := source.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[meta.ReconcileRequestAnnotation] = token
+	source.SetAnnotations(annotations)
+
+	logger.Log("Requesting reconciliation of %s %s", kind, key)
+	if err := c.Patch(ctx, source, patch); err != nil {
+		return fmt.Errorf("patching %s %s: %w", kind, key, err)
+	}
+
+	err := wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		if err := c.Get(ctx, key, source); err != nil {
+			return false, err
+		}
+		return lastHandledReconcileAt() == token, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %s %s to handle the reconcile request: %w", kind, key, err)
+	}
+	return nil
+}
+
+// newReconcileToken returns a unique value for a reconcile request annotation, the same format as the flux CLI uses
+func newReconcileToken() string {
+	return time.Now().Format(time.RFC3339Nano)
+}
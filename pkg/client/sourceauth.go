@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+	. "github.com/onsi/gomega"    //nolint:staticcheck
+)
+
+const (
+	// EnvRegistryUsername is the env var containing the username used to pull from a private registry.
+	EnvRegistryUsername = "E2E_HELM_REGISTRY_USERNAME"
+	// EnvRegistryPassword is the env var containing the password used to pull from a private registry.
+	EnvRegistryPassword = "E2E_HELM_REGISTRY_PASSWORD"
+)
+
+// SourceAuth configures the authentication and verification of the source CR created for a HelmRelease.
+type SourceAuth struct {
+	// SecretName is the name of an existing Secret, in the source namespace, containing the registry
+	// credentials (spec.secretRef). Takes precedence over UseLocalCredentials.
+	SecretName string
+	// UseLocalCredentials creates a `{sourceName}-auth` Secret with the credentials for the source's registry.
+	// These are taken from the E2E_HELM_REGISTRY_USERNAME / E2E_HELM_REGISTRY_PASSWORD env vars if set,
+	// otherwise from the local docker config (`$DOCKER_CONFIG/config.json` or `~/.docker/config.json`).
+	UseLocalCredentials bool
+	// CAFile is the path to a PEM encoded CA bundle used to verify the registry's TLS certificate.
+	// It is stored in a `{sourceName}-ca` Secret referenced via spec.certSecretRef.
+	CAFile string
+	// Provider is the OIDC provider used for authentication (`aws`, `azure`, `gcp` or `generic`).
+	Provider string
+	// Verify enables signature verification of the OCI artifact (only supported on OCIRepositories).
+	// If Verify.SecretRef isn't set and VerifyFiles are provided, it is set to the created Secret.
+	Verify *sourcev1.OCIRepositoryVerification
+	// VerifyFiles are stored in a `{sourceName}-verify` Secret, keyed by their file name, for use by Verify.
+	// For cosign these are the public keys (`*.pub`), for notation the `trustpolicy.json` and certificates.
+	VerifyFiles []string
+}
+
+// sourceAuthRefs holds the references to set on a source CR for its authentication and verification
+type sourceAuthRefs struct {
+	secretRef     *meta.LocalObjectReference
+	certSecretRef *meta.LocalObjectReference
+	provider      string
+	verify        *sourcev1.OCIRepositoryVerification
+}
+
+// ensureSourceAuth creates the Secrets needed by the source's authentication and verification config
+// and returns the references to set on the source CR.
+func ensureSourceAuth(ctx context.Context, sourceName, namespace, url string, auth *SourceAuth) sourceAuthRefs {
+	GinkgoHelper()
+
+	refs := sourceAuthRefs{}
+	if auth == nil {
+		return refs
+	}
+	refs.provider = auth.Provider
+
+	switch {
+	case auth.SecretName != "":
+		refs.secretRef = &meta.LocalObjectReference{Name: auth.SecretName}
+	case auth.UseLocalCredentials:
+		host := registryHost(url)
+		username, password, err := localRegistryCredentials(host)
+		Expect(err).NotTo(HaveOccurred())
+
+		secret := &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: sourceName + "-auth", Namespace: namespace},
+		}
+		if strings.HasPrefix(url, "oci://") {
+			dockerConfig, err := dockerConfigJSON(host, username, password)
+			Expect(err).NotTo(HaveOccurred())
+			secret.Type = corev1.SecretTypeDockerConfigJson
+			secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig}
+		} else {
+			secret.StringData = map[string]string{"username": username, "password": password}
+		}
+		ensureSecret(ctx, secret)
+		refs.secretRef = &meta.LocalObjectReference{Name: secret.Name}
+	}
+
+	if auth.CAFile != "" {
+		ca, err := os.ReadFile(auth.CAFile) // #nosec G304
+		Expect(err).NotTo(HaveOccurred())
+
+		secret := &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: sourceName + "-ca", Namespace: namespace},
+			Data:       map[string][]byte{"ca.crt": ca},
+		}
+		ensureSecret(ctx, secret)
+		refs.certSecretRef = &meta.LocalObjectReference{Name: secret.Name}
+	}
+
+	if auth.Verify != nil {
+		verify := auth.Verify.DeepCopy()
+		if len(auth.VerifyFiles) > 0 {
+			secret := &corev1.Secret{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+				ObjectMeta: metav1.ObjectMeta{Name: sourceName + "-verify", Namespace: namespace},
+				Data:       map[string][]byte{},
+			}
+			for _, file := range auth.VerifyFiles {
+				content, err := os.ReadFile(file) // #nosec G304
+				Expect(err).NotTo(HaveOccurred())
+				secret.Data[filepath.Base(file)] = content
+			}
+			ensureSecret(ctx, secret)
+			if verify.SecretRef == nil {
+				verify.SecretRef = &meta.LocalObjectReference{Name: secret.Name}
+			}
+		}
+		refs.verify = verify
+	}
+
+	return refs
+}
+
+// deleteSourceAuthSecrets deletes the Secrets created by ensureSourceAuth. Not found errors are ignored.
+func deleteSourceAuthSecrets(ctx context.Context, sourceName, namespace string, auth *SourceAuth) error {
+	if auth == nil {
+		return nil
+	}
+
+	names := []string{}
+	if auth.SecretName == "" && auth.UseLocalCredentials {
+		names = append(names, sourceName+"-auth")
+	}
+	if auth.CAFile != "" {
+		names = append(names, sourceName+"-ca")
+	}
+	if auth.Verify != nil && len(auth.VerifyFiles) > 0 {
+		names = append(names, sourceName+"-verify")
+	}
+
+	for _, name := range names {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		err := state.GetFramework().MC().Delete(ctx, secret)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("deleting Secret %s/%s: %w", namespace, name, err)
+		}
+	}
+	return nil
+}
+
+// ensureSecret creates or updates the given Secret, ensuring its namespace exists
+func ensureSecret(ctx context.Context, secret *corev1.Secret) {
+	GinkgoHelper()
+
+	ensureNamespace(ctx, secret.Namespace)
+
+	logger.Log("Ensuring Secret %s/%s", secret.Namespace, secret.Name)
+	err := state.GetFramework().MC().CreateOrUpdate(ctx, secret)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// registryHost returns the registry host of a source URL, e.g. `gsoci.azurecr.io` for `oci://gsoci.azurecr.io/charts`
+func registryHost(url string) string {
+	host := url
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	return host
+}
+
+// localRegistryCredentials returns the credentials for the given registry host from the
+// E2E_HELM_REGISTRY_USERNAME / E2E_HELM_REGISTRY_PASSWORD env vars or the local docker config.
+func localRegistryCredentials(host string) (string, string, error) {
+	if username := os.Getenv(EnvRegistryUsername); username != "" {
+		return username, os.Getenv(EnvRegistryPassword), nil
+	}
+
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", err
+		}
+		configDir = filepath.Join(home, ".docker")
+	}
+
+	content, err := os.ReadFile(filepath.Join(configDir, "config.json")) // #nosec G304
+	if err != nil {
+		return "", "", fmt.Errorf("no registry credentials found in `%s` or the docker config: %w", EnvRegistryUsername, err)
+	}
+	return dockerConfigCredentials(content, host)
+}
+
+// dockerConfigCredentials returns the credentials for the given registry host from the content of a docker config file.
+// Credentials stored in a credential helper are not supported.
+func dockerConfigCredentials(content []byte, host string) (string, string, error) {
+	config := struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return "", "", fmt.Errorf("parsing docker config: %w", err)
+	}
+
+	for registry, entry := range config.Auths {
+		if registryHost(registry) != host {
+			continue
+		}
+		if entry.Username != "" {
+			return entry.Username, entry.Password, nil
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("decoding docker config auth for %s: %w", host, err)
+		}
+		username, password, found := strings.Cut(string(decoded), ":")
+		if !found {
+			return "", "", fmt.Errorf("invalid docker config auth for %s", host)
+		}
+		return username, password, nil
+	}
+
+	return "", "", fmt.Errorf("no credentials for registry %s found in the docker config", host)
+}
+
+// dockerConfigJSON returns the content of a `.dockerconfigjson` containing the given credentials
+func dockerConfigJSON(host, username, password string) ([]byte, error) {
+	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return json.Marshal(map[string]any{
+		"auths": map[string]any{
+			host: map[string]string{
+				"username": username,
+				"password": password,
+				"auth":     auth,
+			},
+		},
+	})
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestRegistryHost(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "oci://gsoci.azurecr.io/charts/giantswarm", expected: "gsoci.azurecr.io"},
+		{url: "https://giantswarm.github.io/control-plane-catalog/", expected: "giantswarm.github.io"},
+		{url: "registry.example.com:5000", expected: "registry.example.com:5000"},
+		{url: "https://index.docker.io/v1/", expected: "index.docker.io"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			if host := registryHost(tc.url); host != tc.expected {
+				t.Fatalf("Expected host '%s' but got '%s'", tc.expected, host)
+			}
+		})
+	}
+}
+
+func TestDockerConfigCredentials(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("robot:s3cr3t:with-colon"))
+	config := []byte(`{
+		"auths": {
+			"https://private.example.com": {"auth": "` + auth + `"},
+			"other.example.com": {"username": "user", "password": "pass"},
+			"broken.example.com": {"auth": "bm90LXZhbGlk"}
+		},
+		"credsStore": "desktop"
+	}`)
+
+	tests := []struct {
+		name             string
+		host             string
+		expectedUsername string
+		expectedPassword string
+		expectError      bool
+	}{
+		{
+			name:             "encoded auth",
+			host:             "private.example.com",
+			expectedUsername: "robot",
+			expectedPassword: "s3cr3t:with-colon",
+		},
+		{
+			name:             "username and password",
+			host:             "other.example.com",
+			expectedUsername: "user",
+			expectedPassword: "pass",
+		},
+		{
+			name:        "invalid auth",
+			host:        "broken.example.com",
+			expectError: true,
+		},
+		{
+			name:        "unknown registry",
+			host:        "unknown.example.com",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			username, password, err := dockerConfigCredentials(config, tc.host)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if username != tc.expectedUsername || password != tc.expectedPassword {
+				t.Fatalf("Expected '%s:%s' but got '%s:%s'", tc.expectedUsername, tc.expectedPassword, username, password)
+			}
+		})
+	}
+}
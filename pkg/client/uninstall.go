@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	helmResourcePolicyAnnotation   = "helm.sh/resource-policy"
+)
+
+// helmManagedKinds are the kinds checked for objects left behind after a Helm release has been uninstalled.
+var helmManagedKinds = []schema.GroupVersionKind{
+	{Group: "", Version: "v1", Kind: "Namespace"},
+	{Group: "", Version: "v1", Kind: "ConfigMap"},
+	{Group: "", Version: "v1", Kind: "Secret"},
+	{Group: "", Version: "v1", Kind: "Service"},
+	{Group: "", Version: "v1", Kind: "ServiceAccount"},
+	{Group: "", Version: "v1", Kind: "PersistentVolumeClaim"},
+	{Group: "apps", Version: "v1", Kind: "Deployment"},
+	{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+	{Group: "batch", Version: "v1", Kind: "Job"},
+	{Group: "batch", Version: "v1", Kind: "CronJob"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"},
+	{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"},
+	{Group: "admissionregistration.k8s.io", Version: "v1", Kind: "ValidatingWebhookConfiguration"},
+	{Group: "admissionregistration.k8s.io", Version: "v1", Kind: "MutatingWebhookConfiguration"},
+}
+
+// FindHelmReleaseLeftovers returns the objects belonging to the given Helm release that still exist in the
+// cluster the provided client points at. This includes the Helm release storage Secrets found in the
+// storage namespace and any namespaced or cluster-scoped objects annotated as being managed by the release.
+// Objects annotated with `helm.sh/resource-policy: keep` are intentionally left behind by Helm and are ignored.
+// Each leftover object is returned as a `Kind namespace/name` string.
+func FindHelmReleaseLeftovers(ctx context.Context, c cr.Client, releaseName, releaseNamespace, storageNamespace string) ([]string, error) {
+	leftovers := []string{}
+
+	storageSecrets := &unstructured.UnstructuredList{}
+	storageSecrets.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "SecretList"})
+	err := c.List(ctx, storageSecrets, cr.InNamespace(storageNamespace), cr.MatchingLabels{"owner": "helm", "name": releaseName})
+	if err != nil {
+		return nil, fmt.Errorf("listing Helm storage Secrets in %s: %w", storageNamespace, err)
+	}
+	for _, secret := range storageSecrets.Items {
+		leftovers = append(leftovers, fmt.Sprintf("Secret %s/%s (Helm release storage)", secret.GetNamespace(), secret.GetName()))
+	}
+
+	for _, gvk := range helmManagedKinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := c.List(ctx, list)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("listing %s: %w", gvk.Kind, err)
+		}
+
+		for _, obj := range list.Items {
+			annotations := obj.GetAnnotations()
+			if annotations[helmReleaseNameAnnotation] != releaseName || annotations[helmReleaseNamespaceAnnotation] != releaseNamespace {
+				continue
+			}
+			if annotations[helmResourcePolicyAnnotation] == "keep" {
+				logger.Log("Ignoring %s %s as it is annotated with `%s: keep`", gvk.Kind, objectName(obj), helmResourcePolicyAnnotation)
+				continue
+			}
+			leftovers = append(leftovers, fmt.Sprintf("%s %s", gvk.Kind, objectName(obj)))
+		}
+	}
+
+	sort.Strings(leftovers)
+	return leftovers, nil
+}
+
+func objectName(obj unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/clustertest/v5/pkg/application"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	"github.com/giantswarm/clustertest/v5/pkg/wait"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+	. "github.com/onsi/gomega"    //nolint:staticcheck
+)
+
+// UpdateHelmReleaseValues replaces the values of an installed HelmRelease, stored in the `<name>-values` Secret,
+// then waits until helm-controller has applied them with a new Helm release and the HelmRelease is ready again.
+// Nothing is done if the values haven't changed.
+// Timeout can be controlled via the provided context. The wait is also aborted if the suite context is cancelled.
+// The wait fails immediately if the upgrade with the new values was rolled back or uninstalled by helm-controller.
+func UpdateHelmReleaseValues(ctx context.Context, name, namespace, values string) {
+	GinkgoHelper()
+
+	ctx, cancel := withSuiteContext(ctx)
+	defer cancel()
+
+	c := state.GetFramework().MC()
+
+	hr := &helmv2.HelmRelease{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, hr)
+	Expect(err).NotTo(HaveOccurred())
+
+	secretName := fmt.Sprintf("%s-values", name)
+	hasValuesSecret := false
+	for _, ref := range hr.Spec.ValuesFrom {
+		if ref.Kind == "Secret" && ref.Name == secretName {
+			hasValuesSecret = true
+		}
+	}
+
+	existing := &corev1.Secret{}
+	err = c.Get(ctx, types.NamespacedName{Name: secretName, Namespace: namespace}, existing)
+	if err == nil && hasValuesSecret && string(existing.Data["values.yaml"]) == values {
+		logger.Log("Values of HelmRelease %s/%s are unchanged", namespace, name)
+		return
+	}
+
+	previousRevision := 0
+	if latest := hr.Status.History.Latest(); latest != nil {
+		previousRevision = latest.Version
+	}
+
+	logger.Log("Updating values of HelmRelease %s/%s", namespace, name)
+	createValuesSecret(ctx, secretName, namespace, values)
+
+	if !hasValuesSecret {
+		// Installed without values, so the Secret isn't referenced yet. Added after the values layers, as on install.
+		patch := cr.MergeFrom(hr.DeepCopy())
+		valuesRef := helmv2.ValuesReference{Kind: "Secret", Name: secretName}
+		valuesFrom := []helmv2.ValuesReference{}
+		added := false
+		for _, ref := range hr.Spec.ValuesFrom {
+			if ref.Kind == "Secret" && ref.Name == fmt.Sprintf("%s-secret-values", name) {
+				// The secret values are applied last
+				valuesFrom = append(valuesFrom, valuesRef)
+				added = true
+			}
+			valuesFrom = append(valuesFrom, ref)
+		}
+		if !added {
+			valuesFrom = append(valuesFrom, valuesRef)
+		}
+		hr.Spec.ValuesFrom = valuesFrom
+		err = c.Patch(ctx, hr, patch)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	// helm-controller doesn't watch the values Secret so the HelmRelease won't be reconciled until its next interval
+	err = ReconcileHelmReleaseNow(ctx, name, namespace, ReconcileOptions{})
+	Expect(err).NotTo(HaveOccurred())
+
+	Eventually(func() (bool, error) {
+		current := &helmv2.HelmRelease{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, current); err != nil {
+			return false, err
+		}
+		if remediated := helmReleaseRemediation(current, previousRevision); remediated != nil {
+			return false, StopTrying("HelmRelease values update was remediated").Wrap(remediated)
+		}
+		if !helmReleaseValuesApplied(current, previousRevision) {
+			logger.Log("HelmRelease %s/%s hasn't released the new values yet (previous revision: %d)", namespace, name, previousRevision)
+			return false, nil
+		}
+		return IsHelmReleaseReady(ctx, name, namespace)
+	}).
+		WithContext(ctx).
+		WithPolling(5 * time.Second).
+		Should(BeTrue())
+
+	logger.Log("HelmRelease %s/%s has been updated with the new values", namespace, name)
+}
+
+// helmReleaseValuesApplied returns true once the HelmRelease has a deployed release newer than the given revision
+func helmReleaseValuesApplied(hr *helmv2.HelmRelease, previousRevision int) bool {
+	history := append(helmv2.Snapshots{}, hr.Status.History...)
+	latest := history.Latest()
+	return latest != nil && latest.Version > previousRevision && latest.Status == "deployed"
+}
+
+// UpdateAppValues replaces the values of an installed App, stored in its user values ConfigMap, then waits until
+// they have been deployed by a new Helm release. The App is updated to use the new values.
+// Nothing is done if the values haven't changed.
+// Timeout can be controlled via the provided context. The wait is also aborted if the suite context is cancelled.
+// The wait fails immediately with an *AppFailedError if the App or its Chart CR reports a terminal failure.
+func UpdateAppValues(ctx context.Context, app *application.Application, values string, config *application.TemplateValues) {
+	GinkgoHelper()
+
+	ctx, cancel := withSuiteContext(ctx)
+	defer cancel()
+
+	_, err := app.WithValues(values, config)
+	Expect(err).NotTo(HaveOccurred())
+
+	_, configMap, err := app.Build()
+	Expect(err).NotTo(HaveOccurred())
+
+	if configMap != nil {
+		existing := &corev1.ConfigMap{}
+		err = state.GetFramework().MC().Get(ctx, cr.ObjectKeyFromObject(configMap), existing)
+		if err == nil && existing.Data["values"] == configMap.Data["values"] {
+			logger.Log("Values of App %s are unchanged", app.InstallName)
+			return
+		}
+	}
+
+	logger.Log("Updating values of App %s", app.InstallName)
+
+	previousStatus := getAppReleaseStatus(ctx, app)
+
+	err = state.GetFramework().MC().DeployApp(ctx, *app)
+	Expect(err).NotTo(HaveOccurred())
+
+	Eventually(stopOnAppFailure(ctx, app, previousStatus, isAppValuesDeployed(ctx, app, previousStatus))).
+		WithContext(ctx).
+		WithPolling(5 * time.Second).
+		Should(BeTrue())
+
+	logger.Log("App %s has been updated with the new values", app.InstallName)
+}
+
+// isAppValuesDeployed returns a wait condition that is met once the App has been deployed again after the given status
+func isAppValuesDeployed(ctx context.Context, app *application.Application, previous appReleaseStatus) wait.WaitCondition {
+	return func() (bool, error) {
+		appCR := &v1alpha1.App{}
+		err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: app.InstallName, Namespace: app.GetNamespace()}, appCR)
+		if err != nil {
+			return false, err
+		}
+
+		current := appReleaseStatusOf(appCR)
+		logger.Log("App status for '%s' is '%s' (last deployed: %s)", app.InstallName, current.status, current.lastDeployed)
+		return appValuesDeployed(current, previous), nil
+	}
+}
+
+// appValuesDeployed returns true once the App has been deployed after the given previous status
+func appValuesDeployed(current, previous appReleaseStatus) bool {
+	return strings.EqualFold(current.status, "deployed") && current.lastDeployed.After(previous.lastDeployed.Time)
+}
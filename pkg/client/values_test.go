@@ -0,0 +1,96 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHelmReleaseValuesApplied(t *testing.T) {
+	tests := []struct {
+		name             string
+		history          helmv2.Snapshots
+		previousRevision int
+		expected         bool
+	}{
+		{
+			name:             "no history",
+			previousRevision: 0,
+			expected:         false,
+		},
+		{
+			name: "no new release",
+			history: helmv2.Snapshots{
+				{Version: 2, Status: "deployed"},
+				{Version: 1, Status: "superseded"},
+			},
+			previousRevision: 2,
+			expected:         false,
+		},
+		{
+			name: "new release pending",
+			history: helmv2.Snapshots{
+				{Version: 2, Status: "deployed"},
+				{Version: 3, Status: "pending-upgrade"},
+			},
+			previousRevision: 2,
+			expected:         false,
+		},
+		{
+			name: "new release deployed",
+			history: helmv2.Snapshots{
+				{Version: 2, Status: "superseded"},
+				{Version: 3, Status: "deployed"},
+			},
+			previousRevision: 2,
+			expected:         true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hr := &helmv2.HelmRelease{Status: helmv2.HelmReleaseStatus{History: tc.history}}
+			if applied := helmReleaseValuesApplied(hr, tc.previousRevision); applied != tc.expected {
+				t.Fatalf("Expected %t but got %t", tc.expected, applied)
+			}
+		})
+	}
+}
+
+func TestAppValuesDeployed(t *testing.T) {
+	before := metav1.NewTime(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	after := metav1.NewTime(before.Add(time.Minute))
+
+	tests := []struct {
+		name     string
+		current  appReleaseStatus
+		expected bool
+	}{
+		{
+			name:     "not deployed again yet",
+			current:  appReleaseStatus{status: "deployed", lastDeployed: before},
+			expected: false,
+		},
+		{
+			name:     "upgrading",
+			current:  appReleaseStatus{status: "pending-upgrade", lastDeployed: after},
+			expected: false,
+		},
+		{
+			name:     "deployed again",
+			current:  appReleaseStatus{status: "deployed", lastDeployed: after},
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			previous := appReleaseStatus{status: "deployed", lastDeployed: before}
+			if deployed := appValuesDeployed(tc.current, previous); deployed != tc.expected {
+				t.Fatalf("Expected %t but got %t", tc.expected, deployed)
+			}
+		})
+	}
+}
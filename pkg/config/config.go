@@ -20,6 +20,22 @@ type AWSConfig struct {
 	Region string `json:"region,omitempty"`
 }
 
+// BundleConfig describes how a bundle App configures its child Apps, for bundles not known to the framework
+type BundleConfig struct {
+	// Naming is the naming convention of the child App keys in the bundle values, either `camelCase`
+	// (e.g. `kyvernoPolicies`), `hyphen` (e.g. `kyverno-policies`) or `none` to not override the child App.
+	// If empty, or `auto`, it's detected from the keys of the child Apps in the bundle's published values.yaml.
+	Naming string `json:"naming,omitempty"`
+
+	// ValuesKeyPath is the dot-separated path of the child Apps within the bundle values.
+	// Defaults to `apps`.
+	ValuesKeyPath string `json:"valuesKeyPath,omitempty"`
+
+	// NamespaceField is the field of a child App's values setting the namespace it's installed into.
+	// Defaults to `namespace`.
+	NamespaceField string `json:"namespaceField,omitempty"`
+}
+
 // TestConfig provides a standard configuration for Apps
 type TestConfig struct {
 	AppName    string   `json:"appName"`
@@ -31,6 +47,10 @@ type TestConfig struct {
 	// AWS contains AWS-specific configuration for tests that need to interact with AWS APIs.
 	// This enables IRSA-based authentication for the test pod.
 	AWS *AWSConfig `json:"aws,omitempty"`
+
+	// Bundles registers how bundle Apps configure their child Apps, by bundle name, extending or
+	// overriding the bundles known to the framework.
+	Bundles map[string]BundleConfig `json:"bundles,omitempty"`
 }
 
 // MustLoad opens the given yaml file and parses it into a TestConfig instance
@@ -0,0 +1,348 @@
+package manifestdiff
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// ChangeType is the type of change made to a resource between the two versions.
+type ChangeType string
+
+const (
+	// ChangeAdded is a resource only found in the new version.
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved is a resource only found in the old version, it will be deleted by the upgrade.
+	ChangeRemoved ChangeType = "removed"
+	// ChangeModified is a resource found in both versions with a different manifest.
+	ChangeModified ChangeType = "modified"
+)
+
+// Resource is a single rendered Kubernetes manifest.
+type Resource struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+	Object     map[string]any
+}
+
+// ID returns the identifier of the resource, e.g. `apps/Deployment/default/hello-world`. The API version
+// isn't included so moving a resource to a new API version isn't seen as removing and adding it.
+func (r Resource) ID() string {
+	group := ""
+	if i := strings.LastIndex(r.APIVersion, "/"); i > 0 {
+		group = r.APIVersion[:i] + "/"
+	}
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s%s/%s", group, r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s%s/%s/%s", group, r.Kind, r.Namespace, r.Name)
+}
+
+// Change is a change made to a single resource between the two versions.
+type Change struct {
+	// Resource is the ID of the changed resource.
+	Resource string
+	// Kind is the kind of the changed resource.
+	Kind string
+	// Type is the type of change.
+	Type ChangeType
+	// Diff is the unified diff between the old and new manifest of the resource.
+	Diff string
+	// Risks lists the reasons this change is likely to break the upgrade or the App.
+	Risks []string
+}
+
+// Result is the result of comparing the rendered manifests of two versions.
+type Result struct {
+	// Changes are the changed resources, sorted by their ID.
+	Changes []Change
+}
+
+// Risks returns all risky changes, prefixed with the resource they were found in.
+func (r Result) Risks() []string {
+	risks := []string{}
+	for _, change := range r.Changes {
+		for _, risk := range change.Risks {
+			risks = append(risks, fmt.Sprintf("%s: %s", change.Resource, risk))
+		}
+	}
+	return risks
+}
+
+// Summary returns a Markdown summary of the changes, listing the risky changes first.
+func (r Result) Summary(oldVersion, newVersion string) string {
+	summary := &strings.Builder{}
+	fmt.Fprintf(summary, "# Rendered manifest changes from %s to %s\n\n", oldVersion, newVersion)
+
+	if len(r.Changes) == 0 {
+		summary.WriteString("No changes.\n")
+		return summary.String()
+	}
+
+	if risks := r.Risks(); len(risks) > 0 {
+		summary.WriteString("## Risky changes\n\n")
+		for _, risk := range risks {
+			fmt.Fprintf(summary, "- %s\n", risk)
+		}
+		summary.WriteString("\n")
+	}
+
+	summary.WriteString("## Changed resources\n\n")
+	for _, change := range r.Changes {
+		fmt.Fprintf(summary, "- %s (%s)\n", change.Resource, change.Type)
+	}
+	return summary.String()
+}
+
+// CompareManifests parses the rendered manifests of the old and new version and compares them.
+func CompareManifests(oldManifests, newManifests string) (Result, error) {
+	oldResources, err := ParseManifests(oldManifests)
+	if err != nil {
+		return Result{}, fmt.Errorf("parsing old manifests: %w", err)
+	}
+	newResources, err := ParseManifests(newManifests)
+	if err != nil {
+		return Result{}, fmt.Errorf("parsing new manifests: %w", err)
+	}
+	return Compare(oldResources, newResources), nil
+}
+
+// ParseManifests parses multi-document YAML, such as the output of `helm template`, into resources.
+// Empty documents are skipped. The values of Secrets are replaced with a hash of them so changes can
+// still be seen without writing the secrets into the report.
+func ParseManifests(manifests string) ([]Resource, error) {
+	resources := []Resource{}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(manifests)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		obj := map[string]any{}
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return nil, err
+		}
+		if len(obj) == 0 {
+			continue
+		}
+
+		resource := Resource{Object: obj}
+		resource.APIVersion, _ = obj["apiVersion"].(string)
+		resource.Kind, _ = obj["kind"].(string)
+		resource.Namespace = nestedString(obj, "metadata", "namespace")
+		resource.Name = nestedString(obj, "metadata", "name")
+		if resource.Kind == "Secret" {
+			hashSecretData(obj)
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// Compare compares the resources of the old and new version, flagging risky changes.
+func Compare(oldResources, newResources []Resource) Result {
+	oldByID := map[string]Resource{}
+	for _, resource := range oldResources {
+		oldByID[resource.ID()] = resource
+	}
+	newByID := map[string]Resource{}
+	for _, resource := range newResources {
+		newByID[resource.ID()] = resource
+	}
+
+	result := Result{}
+	removedServices := []Resource{}
+	addedServices := []Resource{}
+
+	for id, oldResource := range oldByID {
+		newResource, ok := newByID[id]
+		if !ok {
+			change := Change{
+				Resource: id,
+				Kind:     oldResource.Kind,
+				Type:     ChangeRemoved,
+				Diff:     UnifiedDiff(id, id, toYAML(oldResource.Object), ""),
+				Risks:    []string{"removed, it will be deleted by the upgrade"},
+			}
+			if oldResource.Kind == "CustomResourceDefinition" {
+				change.Risks = append(change.Risks, "CRD is removed, Helm keeps CRDs installed from the chart's crds directory but any templated CRD and all its custom resources will be deleted")
+			}
+			if oldResource.Kind == "Service" {
+				removedServices = append(removedServices, oldResource)
+			}
+			result.Changes = append(result.Changes, change)
+			continue
+		}
+
+		if reflect.DeepEqual(oldResource.Object, newResource.Object) {
+			continue
+		}
+		result.Changes = append(result.Changes, Change{
+			Resource: id,
+			Kind:     newResource.Kind,
+			Type:     ChangeModified,
+			Diff:     UnifiedDiff(id, id, toYAML(oldResource.Object), toYAML(newResource.Object)),
+			Risks:    modificationRisks(oldResource, newResource),
+		})
+	}
+
+	for id, newResource := range newByID {
+		if _, ok := oldByID[id]; ok {
+			continue
+		}
+		if newResource.Kind == "Service" {
+			addedServices = append(addedServices, newResource)
+		}
+		result.Changes = append(result.Changes, Change{
+			Resource: id,
+			Kind:     newResource.Kind,
+			Type:     ChangeAdded,
+			Diff:     UnifiedDiff(id, id, "", toYAML(newResource.Object)),
+		})
+	}
+
+	// A Service removed while another with the same selector is added has most likely been renamed. Services
+	// without a selector, such as ExternalName Services, can't be matched.
+	for _, removed := range removedServices {
+		selector, _ := nested(removed.Object, "spec", "selector").(map[string]any)
+		if len(selector) == 0 {
+			continue
+		}
+		for _, added := range addedServices {
+			if removed.Namespace != added.Namespace || !reflect.DeepEqual(nested(removed.Object, "spec", "selector"), nested(added.Object, "spec", "selector")) {
+				continue
+			}
+			for i := range result.Changes {
+				if result.Changes[i].Resource == removed.ID() {
+					result.Changes[i].Risks = append(result.Changes[i].Risks,
+						fmt.Sprintf("Service appears to be renamed to '%s', clients using the old DNS name will break", added.Name))
+				}
+			}
+		}
+	}
+
+	sort.Slice(result.Changes, func(i, j int) bool {
+		return result.Changes[i].Resource < result.Changes[j].Resource
+	})
+	return result
+}
+
+// immutableFields are the fields, by kind, that can't be changed on an existing resource
+var immutableFields = map[string][][]string{
+	"Deployment":            {{"spec", "selector"}},
+	"ReplicaSet":            {{"spec", "selector"}},
+	"DaemonSet":             {{"spec", "selector"}},
+	"StatefulSet":           {{"spec", "selector"}, {"spec", "volumeClaimTemplates"}, {"spec", "serviceName"}, {"spec", "podManagementPolicy"}},
+	"Job":                   {{"spec", "selector"}, {"spec", "template"}, {"spec", "completionMode"}},
+	"Service":               {{"spec", "clusterIP"}},
+	"PersistentVolumeClaim": {{"spec", "storageClassName"}, {"spec", "accessModes"}, {"spec", "volumeName"}},
+	"CustomResourceDefinition": {
+		{"spec", "scope"},
+		{"spec", "group"},
+		{"spec", "names", "kind"},
+		{"spec", "names", "plural"},
+	},
+}
+
+// modificationRisks returns the risky changes between the old and new manifest of a resource
+func modificationRisks(oldResource, newResource Resource) []string {
+	risks := []string{}
+	for _, path := range immutableFields[newResource.Kind] {
+		oldValue := nested(oldResource.Object, path...)
+		newValue := nested(newResource.Object, path...)
+		if !reflect.DeepEqual(oldValue, newValue) {
+			risks = append(risks, fmt.Sprintf("immutable field %s changed, the upgrade will fail unless the resource is recreated", strings.Join(path, ".")))
+		}
+	}
+
+	if newResource.Kind == "CustomResourceDefinition" {
+		risks = append(risks, crdVersionRisks(oldResource, newResource)...)
+	}
+	return risks
+}
+
+// crdVersionRisks returns the CRD versions that are removed, or are no longer served, in the new version
+func crdVersionRisks(oldResource, newResource Resource) []string {
+	newVersions := map[string]map[string]any{}
+	for _, version := range nestedSlice(newResource.Object, "spec", "versions") {
+		if name, _ := version["name"].(string); name != "" {
+			newVersions[name] = version
+		}
+	}
+
+	risks := []string{}
+	for _, oldVersion := range nestedSlice(oldResource.Object, "spec", "versions") {
+		name, _ := oldVersion["name"].(string)
+		newVersion, ok := newVersions[name]
+		switch {
+		case !ok:
+			risks = append(risks, fmt.Sprintf("CRD version %s removed, custom resources stored at it can no longer be read", name))
+		case oldVersion["served"] == true && newVersion["served"] != true:
+			risks = append(risks, fmt.Sprintf("CRD version %s is no longer served, clients using it will break", name))
+		}
+	}
+	return risks
+}
+
+// hashSecretData replaces the values of a Secret's data and stringData with a hash of them
+func hashSecretData(obj map[string]any) {
+	for _, field := range []string{"data", "stringData"} {
+		data, ok := obj[field].(map[string]any)
+		if !ok {
+			continue
+		}
+		for key, value := range data {
+			hash := sha256.Sum256([]byte(fmt.Sprint(value)))
+			data[key] = "sha256:" + hex.EncodeToString(hash[:])[:16]
+		}
+	}
+}
+
+func nested(obj map[string]any, path ...string) any {
+	var current any = obj
+	for _, key := range path {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+func nestedString(obj map[string]any, path ...string) string {
+	value, _ := nested(obj, path...).(string)
+	return value
+}
+
+func nestedSlice(obj map[string]any, path ...string) []map[string]any {
+	items, _ := nested(obj, path...).([]any)
+	result := []map[string]any{}
+	for _, item := range items {
+		if m, ok := item.(map[string]any); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func toYAML(obj map[string]any) string {
+	content, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Sprint(obj)
+	}
+	return string(content)
+}
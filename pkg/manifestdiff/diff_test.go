@@ -0,0 +1,170 @@
+package manifestdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello-world
+  namespace: default
+spec:
+  selector:
+    matchLabels:
+      app: %s
+  template:
+    spec:
+      containers:
+        - name: hello-world
+          image: hello-world:%s
+`
+
+const service = `apiVersion: v1
+kind: Service
+metadata:
+  name: %s
+  namespace: default
+spec:
+  selector:
+    app: hello-world
+`
+
+const crd = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: greetings.example.com
+spec:
+  group: example.com
+  scope: Namespaced
+  names:
+    kind: Greeting
+    plural: greetings
+  versions:
+%s`
+
+func TestCompareManifests(t *testing.T) {
+	tests := []struct {
+		name            string
+		oldManifests    string
+		newManifests    string
+		expectedChanges map[string]ChangeType
+		expectedRisks   []string
+	}{
+		{
+			name:            "no changes",
+			oldManifests:    fmt.Sprintf(deployment, "hello-world", "1.0.0"),
+			newManifests:    fmt.Sprintf(deployment, "hello-world", "1.0.0"),
+			expectedChanges: map[string]ChangeType{},
+		},
+		{
+			name:            "image change",
+			oldManifests:    fmt.Sprintf(deployment, "hello-world", "1.0.0"),
+			newManifests:    fmt.Sprintf(deployment, "hello-world", "1.1.0"),
+			expectedChanges: map[string]ChangeType{"apps/Deployment/default/hello-world": ChangeModified},
+		},
+		{
+			name:            "selector change",
+			oldManifests:    fmt.Sprintf(deployment, "hello-world", "1.0.0"),
+			newManifests:    fmt.Sprintf(deployment, "hello", "1.1.0"),
+			expectedChanges: map[string]ChangeType{"apps/Deployment/default/hello-world": ChangeModified},
+			expectedRisks:   []string{"immutable field spec.selector changed"},
+		},
+		{
+			name:         "removed resource",
+			oldManifests: fmt.Sprintf(deployment, "hello-world", "1.0.0") + "---\n" + fmt.Sprintf(service, "hello-world"),
+			newManifests: fmt.Sprintf(deployment, "hello-world", "1.0.0"),
+			expectedChanges: map[string]ChangeType{
+				"Service/default/hello-world": ChangeRemoved,
+			},
+			expectedRisks: []string{"removed, it will be deleted by the upgrade"},
+		},
+		{
+			name:         "renamed service",
+			oldManifests: fmt.Sprintf(service, "hello-world"),
+			newManifests: fmt.Sprintf(service, "hello-world-http"),
+			expectedChanges: map[string]ChangeType{
+				"Service/default/hello-world":      ChangeRemoved,
+				"Service/default/hello-world-http": ChangeAdded,
+			},
+			expectedRisks: []string{"removed, it will be deleted by the upgrade", "Service appears to be renamed to 'hello-world-http'"},
+		},
+		{
+			name:         "removed service without selector",
+			oldManifests: "apiVersion: v1\nkind: Service\nmetadata:\n  name: hello-world\n  namespace: default\nspec:\n  type: ExternalName\n  externalName: example.com\n",
+			newManifests: "apiVersion: v1\nkind: Service\nmetadata:\n  name: hello-world-headless\n  namespace: default\nspec:\n  clusterIP: None\n",
+			expectedChanges: map[string]ChangeType{
+				"Service/default/hello-world":          ChangeRemoved,
+				"Service/default/hello-world-headless": ChangeAdded,
+			},
+			expectedRisks: []string{"removed, it will be deleted by the upgrade"},
+		},
+		{
+			name:            "removed CRD version",
+			oldManifests:    fmt.Sprintf(crd, "    - name: v1alpha1\n      served: true\n    - name: v1\n      served: true\n"),
+			newManifests:    fmt.Sprintf(crd, "    - name: v1\n      served: true\n"),
+			expectedChanges: map[string]ChangeType{"apiextensions.k8s.io/CustomResourceDefinition/greetings.example.com": ChangeModified},
+			expectedRisks:   []string{"CRD version v1alpha1 removed"},
+		},
+		{
+			name:            "CRD version no longer served",
+			oldManifests:    fmt.Sprintf(crd, "    - name: v1alpha1\n      served: true\n"),
+			newManifests:    fmt.Sprintf(crd, "    - name: v1alpha1\n      served: false\n"),
+			expectedChanges: map[string]ChangeType{"apiextensions.k8s.io/CustomResourceDefinition/greetings.example.com": ChangeModified},
+			expectedRisks:   []string{"CRD version v1alpha1 is no longer served"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := CompareManifests(tc.oldManifests, tc.newManifests)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(result.Changes) != len(tc.expectedChanges) {
+				t.Fatalf("Expected %d changes but got %d: %v", len(tc.expectedChanges), len(result.Changes), result.Changes)
+			}
+			for _, change := range result.Changes {
+				if expected, ok := tc.expectedChanges[change.Resource]; !ok || expected != change.Type {
+					t.Fatalf("Unexpected change %s (%s)", change.Resource, change.Type)
+				}
+				if change.Diff == "" {
+					t.Fatalf("Expected a diff for %s", change.Resource)
+				}
+			}
+
+			risks := result.Risks()
+			if len(risks) != len(tc.expectedRisks) {
+				t.Fatalf("Expected %d risks but got %v", len(tc.expectedRisks), risks)
+			}
+			for i, expected := range tc.expectedRisks {
+				if !strings.Contains(risks[i], expected) {
+					t.Fatalf("Expected risk '%s' but got '%s'", expected, risks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseManifestsHashesSecrets(t *testing.T) {
+	manifests := `apiVersion: v1
+kind: Secret
+metadata:
+  name: hello-world
+stringData:
+  token: s3cr3t
+`
+	resources, err := ParseManifests(manifests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resources) != 1 {
+		t.Fatalf("Expected 1 resource but got %d", len(resources))
+	}
+	if strings.Contains(toYAML(resources[0].Object), "s3cr3t") {
+		t.Fatalf("Expected the secret value to be hashed but got %v", resources[0].Object)
+	}
+}
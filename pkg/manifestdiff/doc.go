@@ -0,0 +1,20 @@
+// package manifestdiff renders Helm charts and compares the rendered manifests of two versions
+//
+// It is used by upgrade test suites to show what an upgrade will change in the cluster, and to flag
+// changes that are likely to break it, such as edits to immutable fields, removed CRD versions,
+// renamed Services and removed resources. Rendering shells out to `helm template`, so the `helm`
+// binary must be available in the PATH.
+//
+// # Example
+//
+//	oldManifests, err := manifestdiff.Render(ctx, manifestdiff.RenderOptions{ReleaseName: "hello-world", Chart: "oci://gsoci.azurecr.io/charts/giantswarm/hello-world", Version: "1.0.0"})
+//	Expect(err).NotTo(HaveOccurred())
+//	newManifests, err := manifestdiff.Render(ctx, manifestdiff.RenderOptions{ReleaseName: "hello-world", Chart: "oci://gsoci.azurecr.io/charts/giantswarm/hello-world", Version: "1.1.0"})
+//	Expect(err).NotTo(HaveOccurred())
+//
+//	result, err := manifestdiff.CompareManifests(oldManifests, newManifests)
+//	Expect(err).NotTo(HaveOccurred())
+//	for _, risk := range result.Risks() {
+//		logger.Log("Risky change: %s", risk)
+//	}
+package manifestdiff
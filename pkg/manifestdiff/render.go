@@ -0,0 +1,78 @@
+package manifestdiff
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ErrHelmNotFound is returned by Render if the `helm` binary can't be found in the PATH.
+var ErrHelmNotFound = errors.New("helm binary not found in PATH")
+
+// RenderOptions configures how a chart is rendered with `helm template`.
+type RenderOptions struct {
+	// ReleaseName is the name of the Helm release to render the chart as.
+	ReleaseName string
+	// Namespace is the namespace of the Helm release.
+	Namespace string
+	// Chart is the chart to render: an `oci://` URL, the name of a chart in RepoURL or a local chart directory.
+	Chart string
+	// RepoURL is the URL of the Helm repository containing Chart. Optional.
+	RepoURL string
+	// Version is the version of the chart to render. Ignored for local chart directories.
+	Version string
+	// Values is the values YAML to render the chart with.
+	Values string
+}
+
+// Render renders the chart with `helm template`, including the CRDs from the chart's `crds` directory,
+// and returns the rendered manifests.
+func Render(ctx context.Context, opts RenderOptions) (string, error) {
+	helm, err := exec.LookPath("helm")
+	if err != nil {
+		return "", ErrHelmNotFound
+	}
+
+	valuesFile, err := os.CreateTemp("", "values-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("creating values file: %w", err)
+	}
+	defer os.Remove(valuesFile.Name()) //nolint:errcheck
+	if _, err := valuesFile.WriteString(opts.Values); err != nil {
+		return "", fmt.Errorf("writing values file: %w", err)
+	}
+	if err := valuesFile.Close(); err != nil {
+		return "", fmt.Errorf("writing values file: %w", err)
+	}
+
+	args := []string{"template", opts.ReleaseName, opts.Chart, "--include-crds", "--values", valuesFile.Name()}
+	if opts.Namespace != "" {
+		args = append(args, "--namespace", opts.Namespace)
+	}
+	if opts.RepoURL != "" {
+		args = append(args, "--repo", opts.RepoURL)
+	}
+	if opts.Version != "" && !isLocalChart(opts.Chart) {
+		args = append(args, "--version", opts.Version)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, helm, args...) // #nosec G204
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("rendering chart %s (version: %s): %w: %s", opts.Chart, opts.Version, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// isLocalChart returns true if the chart reference is a local chart directory
+func isLocalChart(chart string) bool {
+	info, err := os.Stat(chart)
+	return err == nil && info.IsDir()
+}
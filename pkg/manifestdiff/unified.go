@@ -0,0 +1,130 @@
+package manifestdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change in a unified diff
+const contextLines = 3
+
+// maxDiffCells limits the size of the table used to diff two texts, larger texts are shown as fully replaced
+const maxDiffCells = 4_000_000
+
+type diffOp struct {
+	// kind is ' ' for an unchanged line, '-' for a removed line and '+' for an added line
+	kind byte
+	line string
+}
+
+// UnifiedDiff returns the unified diff between the old and new text, or an empty string if they're equal.
+func UnifiedDiff(oldName, newName, oldText, newText string) string {
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	// oldPos and newPos are the number of old / new lines before each op
+	oldPos := make([]int, len(ops)+1)
+	newPos := make([]int, len(ops)+1)
+	hunks := [][2]int{}
+	for i, op := range ops {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if op.kind != '+' {
+			oldPos[i+1]++
+		}
+		if op.kind != '-' {
+			newPos[i+1]++
+		}
+		if op.kind == ' ' {
+			continue
+		}
+
+		start := max(0, i-contextLines)
+		end := min(len(ops), i+contextLines+1)
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1][1] {
+			hunks[len(hunks)-1][1] = max(hunks[len(hunks)-1][1], end)
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	diff := &strings.Builder{}
+	fmt.Fprintf(diff, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range hunks {
+		start, end := hunk[0], hunk[1]
+		oldStart, oldCount := oldPos[start]+1, oldPos[end]-oldPos[start]
+		newStart, newCount := newPos[start]+1, newPos[end]-newPos[start]
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+
+		fmt.Fprintf(diff, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(diff, "%c%s\n", op.kind, op.line)
+		}
+	}
+	return diff.String()
+}
+
+// diffLines returns the operations to turn the old lines into the new lines, based on their longest common subsequence
+func diffLines(oldLines, newLines []string) []diffOp {
+	ops := []diffOp{}
+	if len(oldLines)*len(newLines) > maxDiffCells {
+		for _, line := range oldLines {
+			ops = append(ops, diffOp{kind: '-', line: line})
+		}
+		for _, line := range newLines {
+			ops = append(ops, diffOp{kind: '+', line: line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(oldLines) && j < len(newLines) {
+		switch {
+		case oldLines[i] == newLines[j]:
+			ops = append(ops, diffOp{kind: ' ', line: oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{kind: '-', line: oldLines[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: newLines[j]})
+			j++
+		}
+	}
+	for ; i < len(oldLines); i++ {
+		ops = append(ops, diffOp{kind: '-', line: oldLines[i]})
+	}
+	for ; j < len(newLines); j++ {
+		ops = append(ops, diffOp{kind: '+', line: newLines[j]})
+	}
+	return ops
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
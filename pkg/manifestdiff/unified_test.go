@@ -0,0 +1,54 @@
+package manifestdiff
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		oldText  string
+		newText  string
+		expected string
+	}{
+		{
+			name:     "equal",
+			oldText:  "a\nb\n",
+			newText:  "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "changed line",
+			oldText:  "a\nb\nc\n",
+			newText:  "a\nB\nc\n",
+			expected: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:     "added file",
+			oldText:  "",
+			newText:  "a\nb\n",
+			expected: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "removed file",
+			oldText:  "a\n",
+			newText:  "",
+			expected: "--- old\n+++ new\n@@ -1,1 +0,0 @@\n-a\n",
+		},
+		{
+			name:     "separate hunks",
+			oldText:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			newText:  "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			expected: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := UnifiedDiff("old", "new", tc.oldText, tc.newText)
+			if diff != tc.expected {
+				t.Fatalf("Expected diff:\n%s\nbut got:\n%s", tc.expected, diff)
+			}
+		})
+	}
+}
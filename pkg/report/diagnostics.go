@@ -0,0 +1,130 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DiagnosticsDir is the directory, relative to the suite report directory, that diagnostics are written into.
+const DiagnosticsDir = "diagnostics"
+
+// maxLogBytes limits the size of the container logs collected per container.
+const maxLogBytes = 5 * 1024 * 1024
+
+// CollectObject fetches the current state of the given object and writes it, along with any events
+// referencing it, into the provided directory (relative to the suite report directory).
+// The object only needs its name and namespace set.
+func CollectObject(ctx context.Context, c cr.Client, dir string, obj cr.Object) error {
+	key := cr.ObjectKeyFromObject(obj)
+	if err := c.Get(ctx, key, obj); err != nil {
+		return fmt.Errorf("getting %T %s: %w", obj, key, err)
+	}
+
+	gvk, err := c.GroupVersionKindFor(obj)
+	if err != nil {
+		return fmt.Errorf("getting kind of %s: %w", key, err)
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	baseName := SafeName(fmt.Sprintf("%s-%s-%s", strings.ToLower(gvk.Kind), obj.GetNamespace(), obj.GetName()))
+
+	obj.SetManagedFields(nil)
+	if err := WriteYAML(filepath.Join(dir, baseName+".yaml"), obj); err != nil {
+		return err
+	}
+
+	events := &corev1.EventList{}
+	err = c.List(ctx, events, cr.InNamespace(obj.GetNamespace()), cr.MatchingFields{"involvedObject.name": obj.GetName()})
+	if err != nil {
+		return fmt.Errorf("listing events of %s: %w", key, err)
+	}
+	return WriteYAML(filepath.Join(dir, baseName+"-events.yaml"), events.Items)
+}
+
+// CollectNamespace writes the pod statuses, events and container logs found in the given namespace
+// into the provided directory (relative to the suite report directory).
+// The clientset is used to fetch the container logs and may be nil to skip them.
+func CollectNamespace(ctx context.Context, c cr.Client, clientset kubernetes.Interface, dir, namespace string) error {
+	namespaceDir := filepath.Join(dir, SafeName(namespace))
+
+	events := &corev1.EventList{}
+	if err := c.List(ctx, events, cr.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("listing events in %s: %w", namespace, err)
+	}
+	if err := WriteYAML(filepath.Join(namespaceDir, "events.yaml"), events.Items); err != nil {
+		return err
+	}
+
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, cr.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("listing pods in %s: %w", namespace, err)
+	}
+	for i := range pods.Items {
+		pods.Items[i].ManagedFields = nil
+	}
+	if err := WriteYAML(filepath.Join(namespaceDir, "pods.yaml"), pods.Items); err != nil {
+		return err
+	}
+
+	if clientset == nil {
+		return nil
+	}
+
+	for _, pod := range pods.Items {
+		if err := CollectPodLogs(ctx, clientset, filepath.Join(namespaceDir, "logs"), pod); err != nil {
+			logger.Log("Failed to collect logs of pod %s/%s: %v", namespace, pod.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// CollectPodLogs writes the logs of all containers of the given pod into the provided directory
+// (relative to the suite report directory). The logs of the previous instance of a container are
+// also collected if it has restarted.
+func CollectPodLogs(ctx context.Context, clientset kubernetes.Interface, dir string, pod corev1.Pod) error {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		logPath := filepath.Join(dir, SafeName(fmt.Sprintf("%s_%s", pod.Name, status.Name)))
+		if err := collectLogs(ctx, clientset, pod, status.Name, false, logPath+".log"); err != nil {
+			return err
+		}
+		if status.RestartCount > 0 {
+			if err := collectLogs(ctx, clientset, pod, status.Name, true, logPath+".previous.log"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func collectLogs(ctx context.Context, clientset kubernetes.Interface, pod corev1.Pod, container string, previous bool, path string) error {
+	limit := int64(maxLogBytes)
+	stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		LimitBytes: &limit,
+	}).Stream(ctx)
+	if err != nil {
+		if errors.IsBadRequest(err) {
+			// Container hasn't started yet so there are no logs to collect
+			return nil
+		}
+		return err
+	}
+	defer stream.Close() //nolint:errcheck
+
+	content, err := io.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, content)
+}
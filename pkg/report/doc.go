@@ -0,0 +1,11 @@
+// package report provides helpers for writing files, such as failure diagnostics, into the test report directory
+//
+// Files are written into a directory named after the running test suite within `REPORT_DIR`
+// (defaults to `/tmp/reports`, matching the test container entrypoint) so that they are
+// collected alongside the Ginkgo test results.
+//
+// # Example
+//
+//	err := report.WriteFile("my-test/output.txt", []byte("some output"))
+//	Expect(err).NotTo(HaveOccurred())
+package report
@@ -0,0 +1,59 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+)
+
+// DefaultReportDir is the report directory used if `REPORT_DIR` isn't set.
+const DefaultReportDir = "/tmp/reports"
+
+var unsafePathChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Dir returns the directory that report files for the current test suite are written into.
+func Dir() string {
+	reportDir := os.Getenv("REPORT_DIR")
+	if reportDir == "" {
+		reportDir = DefaultReportDir
+	}
+
+	suiteName := SafeName(state.GetSuiteName())
+	if suiteName == "" {
+		suiteName = "suite"
+	}
+	return filepath.Join(reportDir, suiteName)
+}
+
+// SafeName converts the provided name into something that can safely be used as a file or directory name.
+func SafeName(name string) string {
+	return strings.Trim(unsafePathChars.ReplaceAllString(name, "-"), "-")
+}
+
+// WriteFile writes the content to the given path, relative to the suite report directory.
+// Any missing parent directories are created.
+func WriteFile(path string, content []byte) error {
+	fullPath := filepath.Join(Dir(), path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o750); err != nil {
+		return fmt.Errorf("creating report directory: %w", err)
+	}
+	if err := os.WriteFile(fullPath, content, 0o600); err != nil {
+		return fmt.Errorf("writing report file %s: %w", fullPath, err)
+	}
+	return nil
+}
+
+// WriteYAML marshals the object as YAML and writes it to the given path, relative to the suite report directory.
+func WriteYAML(path string, obj any) error {
+	content, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("marshalling %s: %w", path, err)
+	}
+	return WriteFile(path, content)
+}
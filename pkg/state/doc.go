@@ -3,5 +3,8 @@
 // - Framework - An initialized `clustertest` framework client pointing at the test management cluster
 // - Cluster - A Cluster object with details about the test workload cluster
 // - Application - An Application object with details abou the App being tested
+// - Dependencies - The Apps and HelmReleases installed as dependencies of the App being tested
 // - Context - A context instance
+// - SuiteName - The name of the running test suite
+// - ValuesVariant - The name of the values variant currently installed, if using values variants
 package state
@@ -17,7 +17,11 @@ type state struct {
 	application       *application.Application
 	bundleApplication *application.Application
 	helmRelease       *helmv2.HelmRelease
+	dependencyApps    []*application.Application
+	dependencyHRs     []*helmv2.HelmRelease
 	ctx               context.Context
+	suiteName         string
+	valuesVariant     string
 }
 
 var singleInstance *state
@@ -87,3 +91,39 @@ func SetHelmRelease(hr *helmv2.HelmRelease) {
 func GetHelmRelease() *helmv2.HelmRelease {
 	return get().helmRelease
 }
+
+func AddDependencyApplication(app *application.Application) {
+	s := get()
+	s.dependencyApps = append(s.dependencyApps, app)
+}
+
+func GetDependencyApplications() []*application.Application {
+	return get().dependencyApps
+}
+
+func AddDependencyHelmRelease(hr *helmv2.HelmRelease) {
+	s := get()
+	s.dependencyHRs = append(s.dependencyHRs, hr)
+}
+
+func GetDependencyHelmReleases() []*helmv2.HelmRelease {
+	return get().dependencyHRs
+}
+
+func SetSuiteName(name string) {
+	s := get()
+	s.suiteName = name
+}
+
+func GetSuiteName() string {
+	return get().suiteName
+}
+
+func SetValuesVariant(name string) {
+	s := get()
+	s.valuesVariant = name
+}
+
+func GetValuesVariant() string {
+	return get().valuesVariant
+}
@@ -0,0 +1,48 @@
+package suite
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+)
+
+// cleanupTimeout is the maximum time the cleanup performed in the AfterSuite is allowed to take,
+// excluding the deletion of the workload cluster.
+const cleanupTimeout = 30 * time.Minute
+
+// newSuiteContext returns the context used while running the suite. It is cancelled when the test process
+// is interrupted (e.g. Ctrl-C or ginkgo forwarding an interrupt) or once the Ginkgo suite timeout is reached
+// so that in-flight waits are aborted instead of blocking until their own timeouts.
+//
+// Ginkgo only provides a context per spec, so the signals are handled directly. This doesn't interfere with
+// Ginkgo's own interrupt handling as the signal package delivers each signal to every registered channel,
+// Ginkgo still receives it and runs the AfterSuite cleanup as usual.
+func newSuiteContext(suiteStart time.Time) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if suiteConfig, _ := GinkgoConfiguration(); suiteConfig.Timeout > 0 {
+		deadline := suiteStart.Add(suiteConfig.Timeout)
+		logger.Log("Suite context will be cancelled at %s due to the suite timeout", deadline.Format(time.RFC3339))
+		ctx, cancel = context.WithDeadline(context.Background(), deadline)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// newCleanupContext returns a bounded context for the cleanup steps that isn't cancelled along with the
+// suite context, ensuring the App and its dependencies are still removed after an interrupt or timeout.
+func newCleanupContext(suiteCtx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(suiteCtx), cleanupTimeout)
+}
@@ -0,0 +1,163 @@
+package suite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/clustertest/v5/pkg/application"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+	. "github.com/onsi/gomega"    //nolint:staticcheck
+)
+
+// dependency is an App that needs to be installed before the App being tested.
+// Only one of app or helmRelease is set.
+type dependency struct {
+	app         *application.Application
+	helmRelease *client.HelmReleaseConfig
+}
+
+// WithDependency adds an App that must be installed, and ready, before the App being tested.
+// The dependency can either be an `application.Application` to install as an App CR or a
+// `client.HelmReleaseConfig` to install as a HelmRelease (values or pointers).
+//
+// Dependencies are installed in the order they are added, after the cluster is ready, and are
+// uninstalled in reverse order after the App being tested has been uninstalled.
+// For workload cluster tests the cluster details (cluster name, organization, namespace and
+// kubeconfig Secret) are filled in if not set and the install name is prefixed with the cluster name.
+func (s *suite) WithDependency(dep any) *suite {
+	switch d := dep.(type) {
+	case application.Application:
+		s.dependencies = append(s.dependencies, dependency{app: &d})
+	case *application.Application:
+		s.dependencies = append(s.dependencies, dependency{app: d})
+	case client.HelmReleaseConfig:
+		s.dependencies = append(s.dependencies, dependency{helmRelease: &d})
+	case *client.HelmReleaseConfig:
+		s.dependencies = append(s.dependencies, dependency{helmRelease: d})
+	default:
+		panic(fmt.Sprintf("unsupported dependency type %T, must be an application.Application or client.HelmReleaseConfig", dep))
+	}
+	return s
+}
+
+// installDependencies installs all dependencies, in order, and waits for each to be ready.
+func (s *suite) installDependencies() {
+	GinkgoHelper()
+
+	for _, dep := range s.dependencies {
+		if dep.app != nil {
+			app := s.withDependencyClusterDetails(dep.app)
+
+			ctx, cancel := context.WithTimeout(state.GetContext(), 5*time.Minute)
+			client.InstallApp(ctx, app)
+			cancel()
+
+			state.AddDependencyApplication(app)
+		} else {
+			cfg := s.withDependencyHelmReleaseClusterDetails(*dep.helmRelease)
+
+			ctx, cancel := context.WithTimeout(state.GetContext(), s.getHelmInstallTimeout())
+			client.InstallHelmRelease(ctx, cfg)
+			cancel()
+
+			// InstallHelmRelease stores the HelmRelease in the state as the one under test so move it
+			state.AddDependencyHelmRelease(state.GetHelmRelease())
+			state.SetHelmRelease(nil)
+		}
+	}
+}
+
+// uninstallDependencies removes all installed dependencies in the reverse order they were installed.
+func (s *suite) uninstallDependencies() {
+	GinkgoHelper()
+
+	for i := len(s.dependencies) - 1; i >= 0; i-- {
+		dep := s.dependencies[i]
+		if dep.app != nil {
+			app := s.withDependencyClusterDetails(dep.app)
+			logger.Log("Uninstalling dependency App %s (%s)", app.AppName, app.InstallName)
+			err := state.GetFramework().MC().DeleteApp(state.GetContext(), *app)
+			Expect(err).NotTo(HaveOccurred())
+		} else {
+			cfg := s.withDependencyHelmReleaseClusterDetails(*dep.helmRelease)
+			logger.Log("Uninstalling dependency HelmRelease %s/%s", cfg.Namespace, cfg.Name)
+			err := client.DeleteHelmRelease(state.GetContext(), cfg.Name, cfg.Namespace)
+			Expect(err).NotTo(HaveOccurred())
+			err = client.DeleteHelmValuesLayers(state.GetContext(), cfg)
+			Expect(err).NotTo(HaveOccurred())
+			err = client.DeleteHelmSource(state.GetContext(), cfg)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}
+}
+
+// withDependencyClusterDetails returns a copy of the dependency App targeting the test cluster
+func (s *suite) withDependencyClusterDetails(dep *application.Application) *application.Application {
+	app := *dep
+	if s.isMCTest {
+		return &app
+	}
+
+	cluster := state.GetCluster()
+	if app.ClusterName == "" {
+		app.WithClusterName(cluster.Name)
+		app.WithOrganization(*cluster.Organization)
+	}
+	if !strings.HasPrefix(app.InstallName, cluster.Name+"-") {
+		app.InstallName = fmt.Sprintf("%s-%s", cluster.Name, app.InstallName)
+	}
+	return &app
+}
+
+// withDependencyHelmReleaseClusterDetails returns a copy of the dependency HelmRelease config targeting the test cluster
+func (s *suite) withDependencyHelmReleaseClusterDetails(cfg client.HelmReleaseConfig) client.HelmReleaseConfig {
+	if s.isMCTest {
+		return cfg
+	}
+
+	cluster := state.GetCluster()
+	if cfg.Namespace == "" {
+		cfg.Namespace = cluster.Organization.GetNamespace()
+	}
+	if cfg.KubeConfigSecretName == "" {
+		cfg.KubeConfigSecretName = fmt.Sprintf("%s-kubeconfig", cluster.Name)
+	}
+	if !strings.HasPrefix(cfg.Name, cluster.Name+"-") {
+		cfg.Name = fmt.Sprintf("%s-%s", cluster.Name, cfg.Name)
+	}
+	return cfg
+}
+
+// getHelmDependsOn returns the HelmReleases the App's HelmRelease depends on. References to HelmReleases
+// installed via WithDependency are updated to the name and namespace they were installed with.
+func (s *suite) getHelmDependsOn() []helmv2.DependencyReference {
+	if len(s.helmDependsOn) == 0 {
+		return nil
+	}
+
+	dependsOn := []helmv2.DependencyReference{}
+	for _, ref := range s.helmDependsOn {
+		for _, dep := range s.dependencies {
+			if dep.helmRelease == nil || dep.helmRelease.Name != ref.Name {
+				continue
+			}
+			if ref.Namespace != "" && dep.helmRelease.Namespace != "" && ref.Namespace != dep.helmRelease.Namespace {
+				continue
+			}
+			cfg := s.withDependencyHelmReleaseClusterDetails(*dep.helmRelease)
+			ref.Name = cfg.Name
+			ref.Namespace = cfg.Namespace
+			break
+		}
+		dependsOn = append(dependsOn, ref)
+	}
+	return dependsOn
+}
@@ -0,0 +1,186 @@
+package suite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/clustertest/v5/pkg/application"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/report"
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+)
+
+// collectDiagnostics writes the state of the App (or HelmRelease) being tested, its source and
+// the workloads in its install namespace into the report directory to help debug failed suites.
+// Any errors are logged rather than failing the suite so the cleanup can still take place.
+func (s *suite) collectDiagnostics() {
+	ctx, cancel := context.WithTimeout(state.GetContext(), 5*time.Minute)
+	defer cancel()
+
+	logger.Log("Collecting failure diagnostics into %s", filepath.Join(report.Dir(), report.DiagnosticsDir))
+
+	logError := func(err error) {
+		if err != nil {
+			logger.Log("Failed to collect diagnostics: %v", err)
+		}
+	}
+
+	cluster := state.GetCluster()
+	if state.GetFramework() == nil || cluster == nil {
+		logger.Log("Not connected to the test cluster - skipping diagnostics")
+		return
+	}
+	mcClient := state.GetFramework().MC()
+	mcDir := filepath.Join(report.DiagnosticsDir, "mc")
+
+	var clusterClient cr.Client = mcClient
+	clusterDir := mcDir
+	if !s.isMCTest {
+		clusterDir = filepath.Join(report.DiagnosticsDir, "wc")
+		wcClient, err := state.GetFramework().WC(cluster.Name)
+		if err != nil {
+			logError(err)
+			return
+		}
+		clusterClient = wcClient
+	}
+
+	installNamespace := s.installNamespace
+	if s.useHelmRelease {
+		cfg := s.buildHelmReleaseConfig(s.getHelmReleaseName(), "")
+		if cfg.TargetNamespace != "" {
+			installNamespace = cfg.TargetNamespace
+		} else {
+			installNamespace = cfg.Namespace
+		}
+
+		logError(report.CollectObject(ctx, mcClient, mcDir, &helmv2.HelmRelease{ObjectMeta: v1.ObjectMeta{Name: cfg.Name, Namespace: cfg.Namespace}}))
+		for _, source := range helmSourceObjects(cfg) {
+			logError(report.CollectObject(ctx, mcClient, mcDir, source))
+		}
+	} else {
+		apps := []*application.Application{state.GetApplication()}
+		if bundleApp := state.GetBundleApplication(); bundleApp != nil {
+			apps = append(apps, bundleApp)
+		}
+		for _, app := range apps {
+			logError(report.CollectObject(ctx, mcClient, mcDir, &v1alpha1.App{ObjectMeta: v1.ObjectMeta{Name: app.InstallName, Namespace: app.GetNamespace()}}))
+
+			// The Chart CR is created by app-operator in the cluster the App is installed into
+			chartClient, chartDir := clusterClient, clusterDir
+			if app.InCluster {
+				chartClient, chartDir = mcClient, mcDir
+			}
+			chartName := strings.TrimPrefix(app.InstallName, fmt.Sprintf("%s-", cluster.Name))
+			logError(report.CollectObject(ctx, chartClient, chartDir, &v1alpha1.Chart{ObjectMeta: v1.ObjectMeta{Name: chartName, Namespace: "giantswarm"}}))
+		}
+	}
+
+	var clientset kubernetes.Interface
+	var err error
+	if s.isMCTest {
+		clientset, err = client.GetMCClientset()
+	} else {
+		clientset, err = client.GetWCClientset(ctx, cluster.Name, cluster.Organization.GetNamespace())
+	}
+	logError(err)
+
+	logError(report.CollectNamespace(ctx, clusterClient, clientset, clusterDir, installNamespace))
+}
+
+// helmSourceObjects returns the source CRs used by the HelmRelease built from the given config.
+func helmSourceObjects(cfg client.HelmReleaseConfig) []cr.Object {
+	_ = sourcev1.AddToScheme(state.GetFramework().MC().Scheme())
+	_ = sourcev1beta2.AddToScheme(state.GetFramework().MC().Scheme())
+
+	sourceName := cfg.SourceName
+	if sourceName == "" {
+		sourceName = cfg.ChartName
+	}
+	sourceNamespace := cfg.SourceNamespace
+	if sourceNamespace == "" {
+		sourceNamespace = cfg.Namespace
+	}
+
+	if cfg.SourceKind == client.SourceKindHelmRepository {
+		return []cr.Object{
+			&sourcev1.HelmRepository{ObjectMeta: v1.ObjectMeta{Name: sourceName, Namespace: sourceNamespace}},
+			// The HelmChart is generated by helm-controller as `{namespace}-{name}` of the HelmRelease
+			&sourcev1.HelmChart{ObjectMeta: v1.ObjectMeta{Name: fmt.Sprintf("%s-%s", cfg.Namespace, cfg.Name), Namespace: sourceNamespace}},
+		}
+	}
+	return []cr.Object{
+		&sourcev1beta2.OCIRepository{ObjectMeta: v1.ObjectMeta{Name: sourceName, Namespace: sourceNamespace}},
+	}
+}
+
+// ClusterAccessDir is the directory, relative to the suite report directory, that the details
+// needed to re-attach to a kept test cluster are written into.
+const ClusterAccessDir = "cluster"
+
+// writeClusterAccessDetails writes the name and namespace of the test cluster, as the env vars used to
+// load an existing cluster, and its kubeconfig into the report directory so a failed run can be debugged.
+func (s *suite) writeClusterAccessDetails() {
+	cluster := state.GetCluster()
+	if cluster == nil {
+		return
+	}
+	accessDir := filepath.Join(report.Dir(), ClusterAccessDir)
+
+	if s.isMCTest {
+		logger.Log("Test suite ran against MC '%s' using `E2E_KUBECONFIG`", cluster.Name)
+		return
+	}
+
+	envFile := fmt.Sprintf("E2E_WC_NAME=%s\nE2E_WC_NAMESPACE=%s\nE2E_WC_KEEP=true\n", cluster.Name, cluster.Organization.GetNamespace())
+	if err := report.WriteFile(filepath.Join(ClusterAccessDir, "cluster.env"), []byte(envFile)); err != nil {
+		logger.Log("Failed to write cluster details: %v", err)
+	}
+
+	kubeconfig, err := client.GetWCKubeConfig(state.GetContext(), cluster.Name, cluster.Organization.GetNamespace())
+	if err == nil {
+		err = report.WriteFile(filepath.Join(ClusterAccessDir, "kubeconfig.yaml"), kubeconfig)
+	}
+	if err != nil {
+		logger.Log("Failed to write workload cluster kubeconfig: %v", err)
+	}
+
+	logger.Log("Workload cluster '%s' (namespace: '%s') details written to %s", cluster.Name, cluster.Organization.GetNamespace(), accessDir)
+	logger.Log("Re-attach to the cluster by exporting the env vars in %s or access it directly with `KUBECONFIG=%s`",
+		filepath.Join(accessDir, "cluster.env"), filepath.Join(accessDir, "kubeconfig.yaml"))
+}
+
+// getPauseOnFailure returns the duration to pause for before cleaning up a failed suite, as set by
+// `E2E_PAUSE_ON_FAILURE`. Returns 0 if not set or invalid.
+func getPauseOnFailure() time.Duration {
+	value := os.Getenv("E2E_PAUSE_ON_FAILURE")
+	if value == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Log("Ignoring invalid `E2E_PAUSE_ON_FAILURE` value '%s': %v", value, err)
+		return 0
+	}
+	return duration
+}
+
+// isEnvTrue returns true if the given env var is set to a truthy value
+func isEnvTrue(name string) bool {
+	value, _ := strconv.ParseBool(os.Getenv(name))
+	return value
+}
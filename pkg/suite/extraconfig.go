@@ -0,0 +1,155 @@
+package suite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/clustertest/v5/pkg/application"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/gomega" //nolint:staticcheck
+)
+
+const (
+	// userValuesPriority is the priority app-operator merges the user values at. Extra configs with a
+	// higher priority take precedence over the user values.
+	userValuesPriority = 100
+
+	maxExtraConfigPriority = 150
+)
+
+// extraConfig is a ConfigMap or Secret layered into the App's values with the given priority
+type extraConfig struct {
+	// kind is either `configMap` or `secret`, as used in the App CR
+	kind     string
+	values   string
+	priority int
+}
+
+// WithExtraConfig adds a ConfigMap or Secret (set via kind), created from the content of the values file, to the
+// App's `spec.extraConfigs` with the given priority (1-150). This allows reproducing the precedence of catalog,
+// cluster and user values, e.g. extra configs with a priority above 100 take precedence over the values from
+// WithValuesFile. Extra configs are applied in the order they are added for equal priorities.
+// When installing via a HelmRelease the extra configs are added to spec.valuesFrom, ordered by priority, before or
+// after the values from WithValuesFile accordingly.
+// Panics if the kind or priority is invalid, or the file can't be read.
+func (s *suite) WithExtraConfig(kind, valuesFile string, priority int) *suite {
+	switch strings.ToLower(kind) {
+	case "configmap":
+		kind = "configMap"
+	case "secret":
+		kind = "secret"
+	default:
+		panic(fmt.Sprintf("unsupported extra config kind '%s', must be configMap or secret", kind))
+	}
+	if priority < 1 || priority > maxExtraConfigPriority {
+		panic(fmt.Sprintf("extra config priority must be between 1 and %d, got %d", maxExtraConfigPriority, priority))
+	}
+
+	content, err := os.ReadFile(valuesFile) // #nosec G304
+	if err != nil {
+		panic(fmt.Sprintf("failed to read extra config file %s: %v", valuesFile, err))
+	}
+
+	s.extraConfigs = append(s.extraConfigs, extraConfig{kind: kind, values: string(content), priority: priority})
+	return s
+}
+
+// withExtraConfigs creates the ConfigMaps and Secrets of the extra configs and returns the App with them added
+func (s *suite) withExtraConfigs(ctx context.Context, app *application.Application) (*application.Application, error) {
+	if len(s.extraConfigs) == 0 {
+		return app, nil
+	}
+
+	extraConfigs := append([]v1alpha1.AppExtraConfig{}, app.ExtraConfigs...)
+	s.extraConfigObjects = []cr.Object{}
+	for i, config := range s.extraConfigs {
+		name := fmt.Sprintf("%s-extra-config-%d", app.InstallName, i)
+		objectMeta := metav1.ObjectMeta{Name: name, Namespace: app.GetNamespace()}
+
+		var obj cr.Object = &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: objectMeta,
+			Data:       map[string]string{"values": config.values},
+		}
+		if config.kind == "secret" {
+			obj = &corev1.Secret{
+				TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+				ObjectMeta: objectMeta,
+				StringData: map[string]string{"values": config.values},
+			}
+		}
+
+		logger.Log("Creating extra config %s %s/%s (priority: %d)", config.kind, objectMeta.Namespace, name, config.priority)
+		if err := state.GetFramework().MC().CreateOrUpdate(ctx, obj); err != nil {
+			return nil, err
+		}
+		s.extraConfigObjects = append(s.extraConfigObjects, obj)
+
+		extraConfigs = append(extraConfigs, v1alpha1.AppExtraConfig{
+			Kind:      config.kind,
+			Name:      name,
+			Namespace: app.GetNamespace(),
+			Priority:  config.priority,
+		})
+	}
+
+	return app.WithExtraConfigs(extraConfigs), nil
+}
+
+// deleteExtraConfigs deletes the ConfigMaps and Secrets created for the App's extra configs
+func (s *suite) deleteExtraConfigs() {
+	for _, obj := range s.extraConfigObjects {
+		err := state.GetFramework().MC().Delete(state.GetContext(), obj)
+		if err != nil && !errors.IsNotFound(err) {
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}
+}
+
+// helmExtraConfigLayers returns the extra configs as HelmRelease values layers, ordered by priority as app-operator
+// merges them, split into those merged before the values from WithValuesFile and those merged after
+func (s *suite) helmExtraConfigLayers(installName string) ([]client.ValuesLayer, []client.ValuesLayer) {
+	type indexedConfig struct {
+		extraConfig
+		index int
+	}
+	configs := []indexedConfig{}
+	for i, config := range s.extraConfigs {
+		configs = append(configs, indexedConfig{extraConfig: config, index: i})
+	}
+	sort.SliceStable(configs, func(i, j int) bool {
+		return configs[i].priority < configs[j].priority
+	})
+
+	before := []client.ValuesLayer{}
+	after := []client.ValuesLayer{}
+	for _, config := range configs {
+		layer := client.ValuesLayer{
+			Kind:   "ConfigMap",
+			Name:   fmt.Sprintf("%s-extra-config-%d", installName, config.index),
+			Values: config.values,
+		}
+		if config.kind == "secret" {
+			layer.Kind = "Secret"
+		}
+
+		if config.priority > userValuesPriority {
+			after = append(after, layer)
+		} else {
+			before = append(before, layer)
+		}
+	}
+	return before, after
+}
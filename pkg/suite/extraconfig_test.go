@@ -0,0 +1,65 @@
+package suite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+)
+
+func TestHelmExtraConfigLayers(t *testing.T) {
+	tests := []struct {
+		name           string
+		extraConfigs   []extraConfig
+		expectedBefore []string
+		expectedAfter  []string
+	}{
+		{
+			name:           "no extra configs",
+			expectedBefore: []string{},
+			expectedAfter:  []string{},
+		},
+		{
+			name: "ordered by priority",
+			extraConfigs: []extraConfig{
+				{kind: "configMap", priority: 50},
+				{kind: "secret", priority: 25},
+				{kind: "configMap", priority: 25},
+			},
+			expectedBefore: []string{"Secret/hello-world-extra-config-1", "ConfigMap/hello-world-extra-config-2", "ConfigMap/hello-world-extra-config-0"},
+			expectedAfter:  []string{},
+		},
+		{
+			name: "split around the user values",
+			extraConfigs: []extraConfig{
+				{kind: "configMap", priority: 150},
+				{kind: "configMap", priority: 100},
+				{kind: "secret", priority: 101},
+			},
+			expectedBefore: []string{"ConfigMap/hello-world-extra-config-1"},
+			expectedAfter:  []string{"Secret/hello-world-extra-config-2", "ConfigMap/hello-world-extra-config-0"},
+		},
+	}
+
+	layerNames := func(layers []client.ValuesLayer) []string {
+		names := []string{}
+		for _, layer := range layers {
+			names = append(names, layer.Kind+"/"+layer.Name)
+		}
+		return names
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &suite{extraConfigs: tc.extraConfigs}
+			before, after := s.helmExtraConfigLayers("hello-world")
+
+			if names := layerNames(before); !reflect.DeepEqual(names, tc.expectedBefore) {
+				t.Fatalf("Expected layers before the values %v but got %v", tc.expectedBefore, names)
+			}
+			if names := layerNames(after); !reflect.DeepEqual(names, tc.expectedAfter) {
+				t.Fatalf("Expected layers after the values %v but got %v", tc.expectedAfter, names)
+			}
+		})
+	}
+}
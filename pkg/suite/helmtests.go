@@ -0,0 +1,113 @@
+package suite
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+	. "github.com/onsi/gomega"    //nolint:staticcheck
+)
+
+// HelmTestsDir is the directory, relative to the suite report directory, that the logs of the
+// chart's Helm test pods are written into.
+const HelmTestsDir = "helm-tests"
+
+// helmTestPhaseFailed is the phase of a test hook in a HelmRelease's history once it has failed
+const helmTestPhaseFailed = "Failed"
+
+// WithHelmTests sets the suite to run the chart's own Helm tests (`helm.sh/hook: test`) once the
+// version being tested has been installed. For HelmReleases the tests are run by helm-controller via
+// spec.test, for App CRs the test hooks are run on the cluster by the framework.
+func (s *suite) WithHelmTests() *suite {
+	s.helmTests = true
+	return s
+}
+
+// runHelmTests runs the chart's Helm tests against the installed App, or for HelmReleases checks the
+// result of the tests run by helm-controller, and writes the logs of the test pods into the report.
+func (s *suite) runHelmTests() {
+	GinkgoHelper()
+
+	if s.installer != nil {
+		Skip("Custom installer in use - skipping Helm tests")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(state.GetContext(), 15*time.Minute)
+	defer cancel()
+
+	clusterClient, clientset := s.getInstallClusterClients(ctx)
+
+	releaseName, _, storageNamespace := s.getHelmReleaseLocation()
+	reportDir := filepath.Join(HelmTestsDir, releaseName)
+
+	if !s.useHelmRelease {
+		logger.Log("Running Helm tests of release %s/%s", storageNamespace, releaseName)
+		err := client.RunHelmTests(ctx, clusterClient, clientset, releaseName, storageNamespace, reportDir)
+		Expect(err).NotTo(HaveOccurred())
+		return
+	}
+
+	cfg := s.buildHelmReleaseConfig(s.getHelmReleaseName(), "")
+	logger.Log("Waiting for helm-controller to run the Helm tests of HelmRelease %s/%s", cfg.Namespace, cfg.Name)
+
+	hr := &helmv2.HelmRelease{}
+	Eventually(func() (bool, error) {
+		err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: cfg.Name, Namespace: cfg.Namespace}, hr)
+		if err != nil {
+			return false, err
+		}
+		return hr.Status.History.Latest().HasBeenTested(), nil
+	}).
+		WithContext(ctx).
+		WithPolling(10*time.Second).
+		Should(BeTrue(), "Helm tests were not run for HelmRelease %s/%s", cfg.Namespace, cfg.Name)
+
+	latest := hr.Status.History.Latest()
+	for hookName := range latest.GetTestHooks() {
+		namespace, name := latest.Namespace, hookName
+		if parts := strings.SplitN(hookName, "/", 2); len(parts) == 2 {
+			namespace, name = parts[0], parts[1]
+		}
+		err := client.CollectHelmTestLogs(ctx, clusterClient, clientset, reportDir, name, namespace)
+		if err != nil {
+			logger.Log("Failed to collect logs of Helm test %s: %v", hookName, err)
+		}
+	}
+
+	message := ""
+	if condition := apimeta.FindStatusCondition(hr.Status.Conditions, helmv2.TestSuccessCondition); condition != nil {
+		message = condition.Message
+	}
+	Expect(latest.HasTestInPhase(helmTestPhaseFailed)).To(BeFalse(), "Helm tests of HelmRelease %s/%s failed: %s", cfg.Namespace, cfg.Name, message)
+}
+
+// getInstallClusterClients returns a client and clientset for the cluster the App is installed into
+func (s *suite) getInstallClusterClients(ctx context.Context) (cr.Client, kubernetes.Interface) {
+	GinkgoHelper()
+
+	if s.isMCTest {
+		clientset, err := client.GetMCClientset()
+		Expect(err).NotTo(HaveOccurred())
+		return state.GetFramework().MC(), clientset
+	}
+
+	cluster := state.GetCluster()
+	wcClient, err := state.GetFramework().WC(cluster.Name)
+	Expect(err).NotTo(HaveOccurred())
+	clientset, err := client.GetWCClientset(ctx, cluster.Name, cluster.Organization.GetNamespace())
+	Expect(err).NotTo(HaveOccurred())
+	return wcClient, clientset
+}
@@ -0,0 +1,238 @@
+package suite
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/clustertest/v5/pkg/application"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	"github.com/giantswarm/clustertest/v5/pkg/wait"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+)
+
+// Installer handles delivering the App being tested into the test cluster.
+//
+// The suite provides an App CR and a HelmRelease implementation, selected via `WithHelmRelease`.
+// A custom implementation can be provided via `WithInstaller` to test other delivery mechanisms.
+// Details about the App and cluster being tested can be retrieved from the `state` package.
+type Installer interface {
+	// Install installs the given version of the App and waits for it to be ready at that version.
+	// Timeout can be controlled via the provided context.
+	Install(ctx context.Context, version string) error
+	// Upgrade changes the version of the already installed App to the given version (which may be
+	// older when rolling back) and waits for it to be ready at that version.
+	// Timeout can be controlled via the provided context.
+	Upgrade(ctx context.Context, version string) error
+	// IsReady returns true if the App is currently installed and ready. Checked, along with CurrentVersion,
+	// after each install and upgrade of a custom Installer.
+	IsReady(ctx context.Context) (bool, error)
+	// CurrentVersion returns the version of the App that is currently installed.
+	CurrentVersion(ctx context.Context) (string, error)
+	// Uninstall removes the App from the cluster.
+	Uninstall(ctx context.Context) error
+	// Exists returns true if the App is already installed in the cluster.
+	Exists(ctx context.Context) (bool, error)
+}
+
+// WithInstaller sets a custom Installer to use for installing, upgrading and uninstalling the App.
+// If not set, this defaults to installing via an App CR, or via a HelmRelease if `WithHelmRelease` is set.
+func (s *suite) WithInstaller(installer Installer) *suite {
+	s.installer = installer
+	return s
+}
+
+// getInstaller returns the Installer to use for the App being tested
+func (s *suite) getInstaller() Installer {
+	switch {
+	case s.installer != nil:
+		return s.installer
+	case s.useHelmRelease:
+		return &helmReleaseInstaller{s: s}
+	default:
+		return &appInstaller{s: s}
+	}
+}
+
+// appInstaller installs the App via a Giant Swarm App CR, through the bundle App if testing within a bundle
+type appInstaller struct {
+	s *suite
+}
+
+func (i *appInstaller) Install(ctx context.Context, version string) error {
+	app, err := i.build(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	client.InstallApp(ctx, app)
+	return nil
+}
+
+func (i *appInstaller) Upgrade(ctx context.Context, version string) error {
+	// Deploying an App that already exists updates it in place
+	return i.Install(ctx, version)
+}
+
+func (i *appInstaller) IsReady(ctx context.Context) (bool, error) {
+	app := getInstallApp()
+	return wait.IsAppDeployed(ctx, state.GetFramework().MC(), app.InstallName, app.GetNamespace())()
+}
+
+func (i *appInstaller) CurrentVersion(ctx context.Context) (string, error) {
+	app, err := i.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	return app.Status.Version, nil
+}
+
+func (i *appInstaller) Uninstall(ctx context.Context) error {
+	app := getInstallApp()
+	logger.Log("Uninstalling App %s (%s)", app.AppName, app.InstallName)
+	return state.GetFramework().MC().DeleteApp(ctx, *app)
+}
+
+func (i *appInstaller) Exists(ctx context.Context) (bool, error) {
+	_, err := i.get(ctx)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// build returns the App to install at the given version, with its bundle values, extra configs and secret values
+func (i *appInstaller) build(ctx context.Context, version string) (*application.Application, error) {
+	app, err := i.s.withBundleValues(ctx, i.s.getInstallAppWithVersion(version))
+	if err != nil {
+		return nil, err
+	}
+	app, err = i.s.withExtraConfigs(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	return i.s.withSecretValues(ctx, app)
+}
+
+func (i *appInstaller) get(ctx context.Context) (*v1alpha1.App, error) {
+	app := getInstallApp()
+	appCR := &v1alpha1.App{}
+	err := state.GetFramework().MC().Get(ctx, types.NamespacedName{Name: app.InstallName, Namespace: app.GetNamespace()}, appCR)
+	return appCR, err
+}
+
+// withBundleValues creates the bundle values ConfigMap from the bundle values file, if found,
+// and returns the App with it added as an extra config. Apps not installed via a bundle are returned as-is.
+func (s *suite) withBundleValues(ctx context.Context, app *application.Application) (*application.Application, error) {
+	if state.GetBundleApplication() == nil {
+		return app, nil
+	}
+	if _, err := os.Stat(s.bundleValuesFile); err != nil {
+		return app, nil
+	}
+
+	bundleValuesContent, err := os.ReadFile(s.bundleValuesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	configMapName := fmt.Sprintf("%s-bundle-values", app.InstallName)
+	configMap := &corev1.ConfigMap{
+		TypeMeta: v1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: v1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: app.GetNamespace(),
+		},
+		Data: map[string]string{
+			"values": string(bundleValuesContent),
+		},
+	}
+	err = state.GetFramework().MC().CreateOrUpdate(ctx, configMap)
+	if err != nil {
+		return nil, err
+	}
+	s.bundleValuesConfigMap = configMapName
+
+	extraConfigs := append([]v1alpha1.AppExtraConfig{}, app.ExtraConfigs...)
+	extraConfigs = append(extraConfigs, v1alpha1.AppExtraConfig{
+		Kind:      "configMap",
+		Name:      configMapName,
+		Namespace: app.GetNamespace(),
+		Priority:  25,
+	})
+	return app.WithExtraConfigs(extraConfigs), nil
+}
+
+// helmReleaseInstaller installs the App via a Flux HelmRelease CR
+type helmReleaseInstaller struct {
+	s *suite
+}
+
+func (i *helmReleaseInstaller) Install(ctx context.Context, version string) error {
+	cfg := i.s.buildHelmReleaseConfig(i.s.getHelmReleaseName(), version)
+	client.InstallHelmRelease(ctx, cfg)
+	waitForHelmReleaseVersion(ctx, cfg, version)
+	return nil
+}
+
+func (i *helmReleaseInstaller) Upgrade(ctx context.Context, version string) error {
+	cfg := i.s.buildHelmReleaseConfig(i.s.getHelmReleaseName(), version)
+
+	previousRevision, err := client.GetLatestHelmReleaseRevision(ctx, cfg.Name, cfg.Namespace)
+	if err != nil {
+		return err
+	}
+
+	client.UpdateHelmReleaseVersion(ctx, cfg, version)
+	waitForHelmReleaseUpgrade(ctx, cfg, version, previousRevision)
+	return nil
+}
+
+func (i *helmReleaseInstaller) IsReady(ctx context.Context) (bool, error) {
+	cfg := i.s.buildHelmReleaseConfig(i.s.getHelmReleaseName(), "")
+	return client.IsHelmReleaseReady(ctx, cfg.Name, cfg.Namespace)
+}
+
+func (i *helmReleaseInstaller) CurrentVersion(ctx context.Context) (string, error) {
+	cfg := i.s.buildHelmReleaseConfig(i.s.getHelmReleaseName(), "")
+	return client.GetHelmReleaseVersion(ctx, cfg.Name, cfg.Namespace)
+}
+
+func (i *helmReleaseInstaller) Uninstall(ctx context.Context) error {
+	cfg := i.s.buildHelmReleaseConfig(i.s.getHelmReleaseName(), "")
+	logger.Log("Uninstalling HelmRelease %s/%s", cfg.Namespace, cfg.Name)
+	if err := client.DeleteHelmRelease(ctx, cfg.Name, cfg.Namespace); err != nil {
+		return err
+	}
+	if err := client.DeleteHelmValuesLayers(ctx, cfg); err != nil {
+		return err
+	}
+	return client.DeleteHelmSource(ctx, cfg)
+}
+
+func (i *helmReleaseInstaller) Exists(ctx context.Context) (bool, error) {
+	cfg := i.s.buildHelmReleaseConfig(i.s.getHelmReleaseName(), "")
+	hr := &helmv2.HelmRelease{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      cfg.Name,
+			Namespace: cfg.Namespace,
+		},
+	}
+	err := state.GetFramework().MC().Get(ctx, cr.ObjectKeyFromObject(hr), hr)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
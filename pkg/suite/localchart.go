@@ -0,0 +1,54 @@
+package suite
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+
+	. "github.com/onsi/gomega" //nolint:staticcheck
+)
+
+// WithLocalChart sets the suite to test the chart in the given directory of the working tree instead of a
+// published version. The chart is packaged and pushed to the OCI registry set in `E2E_LOCAL_CHART_REGISTRY`
+// before the suite runs and installed via a HelmRelease with an OCIRepository pinned to the pushed digest.
+//
+// The chart is pushed with the version set in `E2E_APP_VERSION` if set, otherwise with the version from its
+// Chart.yaml suffixed with a unique `-local.{timestamp}` pre-release.
+func (s *suite) WithLocalChart(chartDir string) *suite {
+	s.localChartDir, _ = filepath.Abs(chartDir)
+	s.useHelmRelease = true
+	s.helmSourceKind = client.SourceKindOCIRepository
+	return s
+}
+
+// pushLocalChart packages and pushes the local chart. The rest of the suite installs the version it was pushed
+// with instead of `E2E_APP_VERSION`.
+func (s *suite) pushLocalChart(ctx context.Context, version string) {
+	if version == "latest" {
+		version = ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	localChart, err := client.PushLocalChart(ctx, s.localChartDir, version)
+	Expect(err).NotTo(HaveOccurred())
+
+	logger.Log("Testing local chart %s (version: %s) from %s", localChart.Name, localChart.Version, localChart.URL)
+	s.localChart = localChart
+}
+
+// withLocalChartSource points the HelmRelease config at the pushed local chart when installing its version
+func (s *suite) withLocalChartSource(cfg client.HelmReleaseConfig) client.HelmReleaseConfig {
+	if s.localChart == nil || (cfg.ChartVersion != "" && cfg.ChartVersion != s.localChart.Version) {
+		return cfg
+	}
+	cfg.SourceURL = s.localChart.URL
+	cfg.SourceDigest = s.localChart.Digest
+	cfg.SourceInsecure = s.localChart.Insecure
+	return cfg
+}
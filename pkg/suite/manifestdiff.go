@@ -0,0 +1,283 @@
+package suite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/manifestdiff"
+	"github.com/giantswarm/apptest-framework/v5/pkg/report"
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+)
+
+const (
+	// manifestDiffDir is the directory, relative to the suite report directory, the manifest diff is written into
+	manifestDiffDir = "manifest-diff"
+
+	// giantSwarmOCIChartsURL is the OCI registry the charts of all Giant Swarm catalogs are published to
+	giantSwarmOCIChartsURL = "oci://gsoci.azurecr.io/charts"
+)
+
+// WithManifestDiff sets if upgrade suites render the chart at the installed previous version and at the version
+// to test before upgrading, and write a per-resource diff of the rendered manifests, along with any risky changes,
+// into the `manifest-diff` directory of the report. Requires the `helm` binary in the PATH.
+// The diff is informational only and never fails the suite.
+// If not set this defaults to `true`.
+func (s *suite) WithManifestDiff(enabled bool) *suite {
+	s.manifestDiff = enabled
+	return s
+}
+
+// diffManifests renders the chart at the currently installed version and the version to test, with the values
+// the suite applies, and writes the differences into the report
+func (s *suite) diffManifests() {
+	switch {
+	case s.installer != nil:
+		Skip("Using a custom Installer - skipping manifest diff")
+		return
+	case state.GetBundleApplication() != nil:
+		Skip("App is installed via a bundle - skipping manifest diff")
+		return
+	case s.isDefaultApp && !s.useHelmRelease:
+		Skip("App is a default app - skipping manifest diff")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(state.GetContext(), 5*time.Minute)
+	defer cancel()
+
+	if err := s.writeManifestDiff(ctx); err != nil {
+		logger.Log("Failed to diff the rendered manifests, continuing with the upgrade: %v", err)
+	}
+}
+
+// writeManifestDiff renders and compares both versions, writing the summary and per-resource diffs into the report
+func (s *suite) writeManifestDiff(ctx context.Context) error {
+	oldVersion, err := s.getInstaller().CurrentVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("getting installed version: %w", err)
+	}
+	newVersion := s.getAppVersion()
+
+	oldOpts, omitted, err := s.manifestRenderOptions(oldVersion)
+	if err != nil {
+		return err
+	}
+	newOpts, _, err := s.manifestRenderOptions(newVersion)
+	if err != nil {
+		return err
+	}
+
+	logger.Log("Rendering %s at version '%s' and '%s'", oldOpts.Chart, oldVersion, newVersion)
+	oldManifests, err := manifestdiff.Render(ctx, oldOpts)
+	if errors.Is(err, manifestdiff.ErrHelmNotFound) {
+		logger.Log("The helm binary isn't available - skipping manifest diff")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	newManifests, err := manifestdiff.Render(ctx, newOpts)
+	if err != nil {
+		return err
+	}
+
+	result, err := manifestdiff.CompareManifests(oldManifests, newManifests)
+	if err != nil {
+		return err
+	}
+
+	// Rendered manifests may contain the secret values, such as in ConfigMaps or env vars
+	_, secrets, err := s.loadSecretValues()
+	if err != nil {
+		return err
+	}
+	writeRedacted := func(path, content string) error {
+		buf := &bytes.Buffer{}
+		if _, err := newRedactingWriter(buf, secrets).Write([]byte(content)); err != nil {
+			return err
+		}
+		return report.WriteFile(path, buf.Bytes())
+	}
+
+	for _, change := range result.Changes {
+		path := fmt.Sprintf("%s/%s.diff", manifestDiffDir, report.SafeName(change.Resource))
+		if err := writeRedacted(path, change.Diff); err != nil {
+			return err
+		}
+	}
+	summary := result.Summary(oldVersion, newVersion) + omittedValuesSummary(omitted)
+	if err := writeRedacted(manifestDiffDir+"/summary.md", summary); err != nil {
+		return err
+	}
+
+	logger.Log("Upgrading from '%s' to '%s' changes %d rendered resources, see %s/%s", oldVersion, newVersion, len(result.Changes), report.Dir(), manifestDiffDir)
+	for _, risk := range result.Risks() {
+		logger.Log("Risky change: %s", risk)
+	}
+	return nil
+}
+
+// manifestRenderOptions returns the options to render the chart of the App being tested at the given version, with
+// the values layered in the same order as the suite applies them when installing. The values the render can't
+// include, such as those of existing ConfigMaps and Secrets, are also returned.
+//
+// The bundle values layer isn't needed as Apps installed via a bundle aren't diffed, those values configure the
+// bundle chart rather than the chart of the App.
+func (s *suite) manifestRenderOptions(version string) (manifestdiff.RenderOptions, []string, error) {
+	version = strings.TrimPrefix(version, "v")
+
+	var opts manifestdiff.RenderOptions
+	var layers []client.ValuesLayer
+	omitted := []string{}
+	if s.useHelmRelease {
+		cfg := s.buildHelmReleaseConfig(s.getHelmReleaseName(), version)
+		opts = helmReleaseRenderOptions(cfg)
+		if s.localChart != nil && version == s.localChart.Version {
+			opts.Chart = s.localChartDir
+		}
+
+		layers = append(layers, cfg.ValuesFrom...)
+		layers = append(layers, client.ValuesLayer{Values: cfg.Values})
+		layers = append(layers, cfg.ValuesOverrides...)
+		layers = append(layers, client.ValuesLayer{Values: cfg.SecretValues})
+	} else {
+		app := state.GetApplication()
+		opts = manifestdiff.RenderOptions{
+			ReleaseName: app.InstallName,
+			Namespace:   app.InstallNamespace,
+			Chart:       fmt.Sprintf("%s/%s/%s", giantSwarmOCIChartsURL, app.Catalog, app.AppName),
+			Version:     version,
+		}
+
+		before, after := s.helmExtraConfigLayers(app.InstallName)
+		layers = append(layers, before...)
+		layers = append(layers, client.ValuesLayer{Values: app.Values})
+		layers = append(layers, after...)
+		layers = append(layers, client.ValuesLayer{Values: s.secretValues})
+
+		omitted = append(omitted, "the catalog and cluster values app-operator merges into the App's values")
+	}
+
+	values, err := mergeValuesLayers(layers)
+	if err != nil {
+		return opts, nil, err
+	}
+	opts.Values = values
+	return opts, append(omittedValuesLayers(layers), omitted...), nil
+}
+
+// omittedValuesLayers returns the layers referencing an existing ConfigMap or Secret, whose values can't be
+// included in the render
+func omittedValuesLayers(layers []client.ValuesLayer) []string {
+	omitted := []string{}
+	for _, layer := range layers {
+		if layer.Name != "" && strings.TrimSpace(layer.Values) == "" {
+			omitted = append(omitted, fmt.Sprintf("%s %s", layer.Kind, layer.Name))
+		}
+	}
+	return omitted
+}
+
+// omittedValuesSummary returns the section of the summary listing the values not included in the render
+func omittedValuesSummary(omitted []string) string {
+	if len(omitted) == 0 {
+		return ""
+	}
+
+	summary := &strings.Builder{}
+	summary.WriteString("\n## Values not included\n\n")
+	summary.WriteString("The manifests were rendered without these values, so the changes may differ from those applied by the upgrade:\n\n")
+	for _, layer := range omitted {
+		fmt.Fprintf(summary, "- %s\n", layer)
+	}
+	return summary.String()
+}
+
+// helmReleaseRenderOptions returns the options to render the chart of the HelmRelease config as Flux would install it
+func helmReleaseRenderOptions(cfg client.HelmReleaseConfig) manifestdiff.RenderOptions {
+	opts := manifestdiff.RenderOptions{
+		ReleaseName: cfg.ReleaseName,
+		Namespace:   cfg.TargetNamespace,
+		Chart:       cfg.SourceURL,
+		Version:     cfg.ChartVersion,
+	}
+
+	// Flux defaults the release name to `<target namespace>-<name>`
+	if opts.ReleaseName == "" {
+		opts.ReleaseName = cfg.Name
+		if cfg.TargetNamespace != "" {
+			opts.ReleaseName = fmt.Sprintf("%s-%s", cfg.TargetNamespace, cfg.Name)
+		}
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = cfg.Namespace
+	}
+
+	if cfg.SourceKind == client.SourceKindHelmRepository {
+		repoURL := cfg.SourceURL
+		if repoURL == "" {
+			repoURL = client.DefaultGiantSwarmHelmRepositoryURL
+		}
+		if strings.HasPrefix(repoURL, "oci://") {
+			opts.Chart = fmt.Sprintf("%s/%s", strings.TrimSuffix(repoURL, "/"), cfg.ChartName)
+		} else {
+			opts.Chart = cfg.ChartName
+			opts.RepoURL = repoURL
+		}
+	} else if opts.Chart == "" {
+		opts.Chart = fmt.Sprintf("%s/%s", client.DefaultGiantSwarmHelmRepositoryURL, cfg.ChartName)
+	}
+	return opts
+}
+
+// mergeValuesLayers deep merges the values of the layers in order, later layers taking precedence. Layers without
+// values, such as those referencing an existing ConfigMap or Secret, are ignored.
+func mergeValuesLayers(layers []client.ValuesLayer) (string, error) {
+	merged := map[string]any{}
+	for _, layer := range layers {
+		if strings.TrimSpace(layer.Values) == "" {
+			continue
+		}
+
+		if layer.TargetPath != "" {
+			if err := setValuesPath(merged, layer.TargetPath, strings.TrimSpace(layer.Values)); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		values := map[string]any{}
+		if err := yaml.Unmarshal([]byte(layer.Values), &values); err != nil {
+			return "", fmt.Errorf("parsing values of %s %s: %w", layer.Kind, layer.Name, err)
+		}
+		mergeValues(merged, values)
+	}
+
+	content, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// mergeValues deep merges src into dst, as Helm merges values files
+func mergeValues(dst, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			mergeValues(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
@@ -0,0 +1,163 @@
+package suite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/manifestdiff"
+)
+
+func TestMergeValuesLayers(t *testing.T) {
+	tests := []struct {
+		name     string
+		layers   []client.ValuesLayer
+		expected string
+	}{
+		{
+			name:     "no layers",
+			expected: "{}\n",
+		},
+		{
+			name: "later layers take precedence",
+			layers: []client.ValuesLayer{
+				{Values: "image:\n  tag: 1.0.0\n  registry: gsoci.azurecr.io\nreplicas: 1\n"},
+				{Values: "image:\n  tag: 1.1.0\n"},
+				{Values: "replicas: 2\n"},
+			},
+			expected: "image:\n  registry: gsoci.azurecr.io\n  tag: 1.1.0\nreplicas: 2\n",
+		},
+		{
+			name: "lists are replaced",
+			layers: []client.ValuesLayer{
+				{Values: "args:\n- a\n- b\n"},
+				{Values: "args:\n- c\n"},
+			},
+			expected: "args:\n- c\n",
+		},
+		{
+			name: "layers without values are ignored",
+			layers: []client.ValuesLayer{
+				{Kind: "ConfigMap", Name: "existing"},
+				{Values: "replicas: 1\n"},
+			},
+			expected: "replicas: 1\n",
+		},
+		{
+			name: "target path",
+			layers: []client.ValuesLayer{
+				{Values: "auth:\n  enabled: true\n"},
+				{Kind: "Secret", Name: "token", TargetPath: "auth.token", Values: "s3cr3t\n"},
+			},
+			expected: "auth:\n  enabled: true\n  token: s3cr3t\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := mergeValuesLayers(tc.layers)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if values != tc.expected {
+				t.Fatalf("Expected values:\n%s\nbut got:\n%s", tc.expected, values)
+			}
+		})
+	}
+}
+
+func TestHelmReleaseRenderOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      client.HelmReleaseConfig
+		expected manifestdiff.RenderOptions
+	}{
+		{
+			name: "default OCI repository",
+			cfg:  client.HelmReleaseConfig{Name: "hello-world", Namespace: "org-test", ChartName: "hello-world", ChartVersion: "1.0.0"},
+			expected: manifestdiff.RenderOptions{
+				ReleaseName: "hello-world",
+				Namespace:   "org-test",
+				Chart:       "oci://gsoci.azurecr.io/charts/giantswarm/hello-world",
+				Version:     "1.0.0",
+			},
+		},
+		{
+			name: "target namespace",
+			cfg:  client.HelmReleaseConfig{Name: "hello-world", Namespace: "org-test", TargetNamespace: "hello", ChartName: "hello-world", SourceURL: "oci://example.com/charts/hello-world"},
+			expected: manifestdiff.RenderOptions{
+				ReleaseName: "hello-hello-world",
+				Namespace:   "hello",
+				Chart:       "oci://example.com/charts/hello-world",
+			},
+		},
+		{
+			name: "HTTPS Helm repository",
+			cfg:  client.HelmReleaseConfig{Name: "hello-world", Namespace: "org-test", ReleaseName: "hello", ChartName: "hello-world", SourceKind: client.SourceKindHelmRepository, SourceURL: "https://example.com/charts"},
+			expected: manifestdiff.RenderOptions{
+				ReleaseName: "hello",
+				Namespace:   "org-test",
+				Chart:       "hello-world",
+				RepoURL:     "https://example.com/charts",
+			},
+		},
+		{
+			name: "default Helm repository",
+			cfg:  client.HelmReleaseConfig{Name: "hello-world", Namespace: "org-test", ChartName: "hello-world", SourceKind: client.SourceKindHelmRepository},
+			expected: manifestdiff.RenderOptions{
+				ReleaseName: "hello-world",
+				Namespace:   "org-test",
+				Chart:       "oci://gsoci.azurecr.io/charts/giantswarm/hello-world",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := helmReleaseRenderOptions(tc.cfg)
+			if opts != tc.expected {
+				t.Fatalf("Expected %+v but got %+v", tc.expected, opts)
+			}
+		})
+	}
+}
+
+func TestOmittedValuesLayers(t *testing.T) {
+	tests := []struct {
+		name     string
+		layers   []client.ValuesLayer
+		expected []string
+	}{
+		{
+			name:     "no layers",
+			expected: []string{},
+		},
+		{
+			name: "layers with values are included",
+			layers: []client.ValuesLayer{
+				{Kind: "ConfigMap", Name: "hello-world-extra-config-0", Values: "replicas: 1\n"},
+				{Values: "replicas: 2\n"},
+				{Values: ""},
+			},
+			expected: []string{},
+		},
+		{
+			name: "existing ConfigMaps and Secrets are omitted",
+			layers: []client.ValuesLayer{
+				{Kind: "ConfigMap", Name: "cluster-values"},
+				{Values: "replicas: 2\n"},
+				{Kind: "Secret", Name: "credentials", TargetPath: "auth.token"},
+			},
+			expected: []string{"ConfigMap cluster-values", "Secret credentials"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			omitted := omittedValuesLayers(tc.layers)
+			if !reflect.DeepEqual(omitted, tc.expected) {
+				t.Fatalf("Expected %v but got %v", tc.expected, omitted)
+			}
+		})
+	}
+}
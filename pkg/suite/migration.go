@@ -0,0 +1,158 @@
+package suite
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+	. "github.com/onsi/gomega"    //nolint:staticcheck
+)
+
+// instanceLabel is the standard label charts set on their resources to the name of the Helm release
+const instanceLabel = "app.kubernetes.io/instance"
+
+// WithMigrationToHelmRelease sets the suite to test migrating the App from an App CR to a Flux HelmRelease.
+// The version to test is installed via an App CR, then the App CR is removed without uninstalling the chart and
+// the existing Helm release is adopted by a HelmRelease configured via the `WithHelm*` options. The migration
+// fails if any pod of the release, as labelled with `app.kubernetes.io/instance: <release name>`, was recreated
+// or the Helm release revision didn't continue from the one installed by the App CR. The `Tests` are run against
+// the App once it's managed by the HelmRelease.
+//
+// The HelmRelease defaults to the release name, namespace and chart of the App CR.
+// Not supported with `WithHelmRelease`, `WithInstaller` or `InAppBundle`.
+func (s *suite) WithMigrationToHelmRelease() *suite {
+	s.migrateToHelmRelease = true
+	return s
+}
+
+// migrateAppToHelmRelease hands the Helm release installed by the App CR over to a HelmRelease and verifies
+// no workload was recreated by it
+func (s *suite) migrateAppToHelmRelease() {
+	GinkgoHelper()
+
+	if s.isDefaultApp {
+		Skip("App is a default app - skipping migration")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(state.GetContext(), s.getHelmInstallTimeout())
+	defer cancel()
+
+	app := state.GetApplication()
+	appCR, err := (&appInstaller{s: s}).get(ctx)
+	Expect(err).NotTo(HaveOccurred())
+	version := appCR.Spec.Version
+
+	releaseName, releaseNamespace, storageNamespace := s.getHelmReleaseLocation()
+
+	var clusterClient cr.Client = state.GetFramework().MC()
+	if !s.isMCTest {
+		wcClient, err := state.GetFramework().WC(state.GetCluster().Name)
+		Expect(err).NotTo(HaveOccurred())
+		clusterClient = wcClient
+	}
+
+	previousRevision, err := client.GetHelmReleaseStorageRevision(ctx, clusterClient, releaseName, storageNamespace)
+	Expect(err).NotTo(HaveOccurred())
+	podsBefore, err := getReleasePodUIDs(ctx, clusterClient, releaseName, releaseNamespace)
+	Expect(err).NotTo(HaveOccurred())
+	if len(podsBefore) == 0 {
+		logger.Log("No running pods labelled '%s=%s' found in %s, recreated workloads can't be detected", instanceLabel, releaseName, releaseNamespace)
+	}
+	logger.Log("Migrating Helm release %s/%s (revision %d, %d pods) from App CR to HelmRelease", releaseNamespace, releaseName, previousRevision, len(podsBefore))
+
+	Expect(client.DeleteAppKeepingRelease(ctx, app)).To(Succeed())
+
+	// Adopt the release where the App CR installed it, unless configured otherwise
+	if s.helmReleaseName == "" {
+		s.helmReleaseName = releaseName
+	}
+	if s.helmTargetNamespace == "" {
+		s.helmTargetNamespace = releaseNamespace
+	}
+	if s.helmStorageNamespace == "" {
+		s.helmStorageNamespace = storageNamespace
+	}
+	if s.helmSourceURL == "" && s.helmSourceKind != client.SourceKindHelmRepository {
+		s.helmSourceURL = fmt.Sprintf("%s/%s/%s", giantSwarmOCIChartsURL, appCR.Spec.Catalog, appCR.Spec.Name)
+	}
+	// From here on the App is managed via the HelmRelease, including its cleanup
+	s.useHelmRelease = true
+
+	cfg := s.buildHelmReleaseConfig(s.getHelmReleaseName(), version)
+	client.InstallHelmRelease(ctx, cfg)
+	waitForHelmReleaseVersion(ctx, cfg, version)
+
+	revision, err := client.GetHelmReleaseStorageRevision(ctx, clusterClient, releaseName, storageNamespace)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(revision).To(BeNumerically(">", previousRevision),
+		"the HelmRelease didn't continue the Helm release history of the App CR (revision %d before the migration)", previousRevision)
+
+	podsAfter, err := getReleasePodUIDs(ctx, clusterClient, releaseName, releaseNamespace)
+	Expect(err).NotTo(HaveOccurred())
+	recreated := recreatedPods(podsBefore, podsAfter)
+	Expect(recreated).To(BeEmpty(), "pods were recreated by the migration:\n  %s", strings.Join(recreated, "\n  "))
+
+	logger.Log("Helm release %s/%s adopted by HelmRelease %s/%s at revision %d", releaseNamespace, releaseName, cfg.Namespace, cfg.Name, revision)
+}
+
+// validateMigration returns an error if the suite is configured to migrate to a HelmRelease along with an
+// option the migration doesn't support
+func (s *suite) validateMigration() error {
+	if !s.migrateToHelmRelease {
+		return nil
+	}
+	switch {
+	case s.useHelmRelease:
+		return fmt.Errorf("WithMigrationToHelmRelease can't be used with WithHelmRelease")
+	case s.installer != nil:
+		return fmt.Errorf("WithMigrationToHelmRelease can't be used with WithInstaller")
+	case s.inBundleApp != "":
+		return fmt.Errorf("WithMigrationToHelmRelease can't be used with InAppBundle")
+	}
+	return nil
+}
+
+// getReleasePodUIDs returns the UIDs of the running pods of the Helm release, as labelled by the chart with the
+// standard `app.kubernetes.io/instance` label, by pod name. Pods of other releases sharing the namespace are ignored.
+func getReleasePodUIDs(ctx context.Context, c cr.Client, releaseName, namespace string) (map[string]types.UID, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, cr.InNamespace(namespace), cr.MatchingLabels{instanceLabel: releaseName}); err != nil {
+		return nil, err
+	}
+
+	uids := map[string]types.UID{}
+	for _, pod := range pods.Items {
+		// Completed pods, e.g. of Jobs and hooks, are expected to come and go
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		uids[pod.Name] = pod.UID
+	}
+	return uids, nil
+}
+
+// recreatedPods returns the pods from before that are no longer found, or have been replaced by a new pod of the same name
+func recreatedPods(before, after map[string]types.UID) []string {
+	recreated := []string{}
+	for name, uid := range before {
+		switch afterUID, ok := after[name]; {
+		case !ok:
+			recreated = append(recreated, fmt.Sprintf("%s (deleted)", name))
+		case afterUID != uid:
+			recreated = append(recreated, fmt.Sprintf("%s (replaced)", name))
+		}
+	}
+	sort.Strings(recreated)
+	return recreated
+}
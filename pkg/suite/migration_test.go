@@ -0,0 +1,90 @@
+package suite
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRecreatedPods(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   map[string]types.UID
+		after    map[string]types.UID
+		expected []string
+	}{
+		{
+			name:     "no pods",
+			expected: []string{},
+		},
+		{
+			name:     "unchanged",
+			before:   map[string]types.UID{"hello-world-abc": "1", "hello-world-def": "2"},
+			after:    map[string]types.UID{"hello-world-abc": "1", "hello-world-def": "2"},
+			expected: []string{},
+		},
+		{
+			name:     "new pods are ignored",
+			before:   map[string]types.UID{"hello-world-abc": "1"},
+			after:    map[string]types.UID{"hello-world-abc": "1", "hello-world-xyz": "3"},
+			expected: []string{},
+		},
+		{
+			name:     "deleted and replaced",
+			before:   map[string]types.UID{"hello-world-abc": "1", "hello-world-0": "2"},
+			after:    map[string]types.UID{"hello-world-0": "4", "hello-world-xyz": "3"},
+			expected: []string{"hello-world-0 (replaced)", "hello-world-abc (deleted)"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recreated := recreatedPods(tc.before, tc.after)
+			if !reflect.DeepEqual(recreated, tc.expected) {
+				t.Fatalf("Expected %v but got %v", tc.expected, recreated)
+			}
+		})
+	}
+}
+
+func TestValidateMigration(t *testing.T) {
+	tests := []struct {
+		name        string
+		suite       *suite
+		expectedErr bool
+	}{
+		{
+			name:  "not migrating",
+			suite: &suite{useHelmRelease: true},
+		},
+		{
+			name:  "migrating from App CR",
+			suite: &suite{migrateToHelmRelease: true},
+		},
+		{
+			name:        "with HelmRelease",
+			suite:       &suite{migrateToHelmRelease: true, useHelmRelease: true},
+			expectedErr: true,
+		},
+		{
+			name:        "with custom installer",
+			suite:       &suite{migrateToHelmRelease: true, installer: &helmReleaseInstaller{}},
+			expectedErr: true,
+		},
+		{
+			name:        "in bundle",
+			suite:       &suite{migrateToHelmRelease: true, inBundleApp: "security-bundle"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.suite.validateMigration()
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("Expected error: %t but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
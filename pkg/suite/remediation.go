@@ -0,0 +1,39 @@
+package suite
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+	. "github.com/onsi/gomega"    //nolint:staticcheck
+)
+
+// waitForHelmReleaseUpgrade waits for the HelmRelease to be ready with the given version, as waitForHelmReleaseVersion,
+// but fails immediately if helm-controller rolled back or uninstalled a release newer than previousRevision.
+// A failed upgrade that has been rolled back can otherwise report Ready while still running the old version.
+func waitForHelmReleaseUpgrade(ctx context.Context, cfg client.HelmReleaseConfig, version string, previousRevision int) {
+	GinkgoHelper()
+
+	Eventually(func() (bool, error) {
+		var remediated *client.HelmReleaseRemediatedError
+		err := client.CheckHelmReleaseRemediation(state.GetContext(), cfg.Name, cfg.Namespace, previousRevision)
+		if errors.As(err, &remediated) {
+			return false, StopTrying("HelmRelease upgrade was remediated").Wrap(err)
+		} else if err != nil {
+			return false, err
+		}
+
+		ready, err := client.IsHelmReleaseReady(state.GetContext(), cfg.Name, cfg.Namespace)
+		if !ready || err != nil {
+			return false, err
+		}
+		return client.IsHelmReleaseVersion(state.GetContext(), cfg.Name, cfg.Namespace, version)
+	}).
+		WithContext(ctx).
+		WithPolling(5 * time.Second).
+		Should(BeTrue())
+}
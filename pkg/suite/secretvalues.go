@@ -0,0 +1,242 @@
+package suite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/clustertest/v5/pkg/application"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/gomega" //nolint:staticcheck
+)
+
+const (
+	// EnvSecretValuePrefix is the prefix of the env vars that secret values are read from with WithSecretValueFromEnv.
+	// The values of all env vars with this prefix are redacted from the log output.
+	EnvSecretValuePrefix = "E2E_SECRET_VALUE_"
+
+	// secretValuesPriority is the extra config priority of the App's secret values, above the user values so they
+	// take precedence
+	secretValuesPriority = 150
+
+	redacted = "[REDACTED]"
+	// minRedactedLength is the length below which secret values aren't redacted, e.g. `true` or `1`
+	minRedactedLength = 6
+)
+
+// secretValueFromEnv is a single secret value, set at the values path from the E2E_SECRET_VALUE_<KEY> env var
+type secretValueFromEnv struct {
+	valuesPath string
+	key        string
+}
+
+// WithSecretValuesFile sets a values file containing sensitive values, such as API tokens, that is provided to the
+// App via a Secret rather than the user values ConfigMap. For App CRs this is created as the `<install name>-user-secrets`
+// Secret and for HelmReleases as the `<name>-secret-values` Secret, applied on top of the values from `WithValuesFile`.
+// The string values of the file are redacted from the log output.
+// Unlike WithValuesFile, the suite fails if the file can't be read.
+func (s *suite) WithSecretValuesFile(valuesFile string) *suite {
+	s.secretValuesFile, _ = filepath.Abs(valuesFile)
+	return s
+}
+
+// WithSecretValueFromEnv sets the secret value at the given dot-separated values path (e.g. `auth.apiToken`) to the
+// content of the `E2E_SECRET_VALUE_<KEY>` env var. The value is provided to the App alongside, and takes precedence over,
+// the values of WithSecretValuesFile. The suite fails if the env var isn't set.
+func (s *suite) WithSecretValueFromEnv(valuesPath, key string) *suite {
+	s.secretValuesFromEnv = append(s.secretValuesFromEnv, secretValueFromEnv{valuesPath: valuesPath, key: key})
+	return s
+}
+
+// loadSecretValues returns the secret values YAML from the secret values file and env vars, along with the secrets
+// to redact from the log output
+func (s *suite) loadSecretValues() (string, []string, error) {
+	secrets := []string{}
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, EnvSecretValuePrefix) {
+			secrets = append(secrets, value)
+		}
+	}
+
+	if s.secretValuesFile == "" && len(s.secretValuesFromEnv) == 0 {
+		return "", secrets, nil
+	}
+
+	values := map[string]any{}
+	if s.secretValuesFile != "" {
+		content, err := os.ReadFile(s.secretValuesFile) // #nosec G304
+		if err != nil {
+			return "", nil, fmt.Errorf("reading secret values file: %w", err)
+		}
+		if err := yaml.Unmarshal(content, &values); err != nil {
+			return "", nil, fmt.Errorf("parsing secret values file %s: %w", s.secretValuesFile, err)
+		}
+		if values == nil {
+			values = map[string]any{}
+		}
+	}
+
+	for _, secretValue := range s.secretValuesFromEnv {
+		envName := EnvSecretValuePrefix + secretValue.key
+		value, ok := os.LookupEnv(envName)
+		if !ok {
+			return "", nil, fmt.Errorf("`%s` must be set for the secret value '%s'", envName, secretValue.valuesPath)
+		}
+		if err := setValuesPath(values, secretValue.valuesPath, value); err != nil {
+			return "", nil, err
+		}
+	}
+
+	secrets = append(secrets, secretStrings(values)...)
+
+	content, err := yaml.Marshal(values)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(content), secrets, nil
+}
+
+// setValuesPath sets the value at the dot-separated path, creating any missing parent maps
+func setValuesPath(values map[string]any, valuesPath, value string) error {
+	keys := strings.Split(valuesPath, ".")
+	current := values
+	for i, key := range keys[:len(keys)-1] {
+		next, ok := current[key]
+		if !ok {
+			next = map[string]any{}
+			current[key] = next
+		}
+		nextMap, ok := next.(map[string]any)
+		if !ok {
+			return fmt.Errorf("can't set '%s', '%s' isn't a map", valuesPath, strings.Join(keys[:i+1], "."))
+		}
+		current = nextMap
+	}
+	current[keys[len(keys)-1]] = value
+	return nil
+}
+
+// secretStrings returns all string values within the values
+func secretStrings(values any) []string {
+	secrets := []string{}
+	switch v := values.(type) {
+	case map[string]any:
+		for _, value := range v {
+			secrets = append(secrets, secretStrings(value)...)
+		}
+	case []any:
+		for _, value := range v {
+			secrets = append(secrets, secretStrings(value)...)
+		}
+	case string:
+		secrets = append(secrets, v)
+	}
+	return secrets
+}
+
+// redactingWriter replaces any secrets written with a placeholder
+type redactingWriter struct {
+	w       io.Writer
+	secrets []string
+}
+
+// newRedactingWriter returns a writer redacting the given secrets before writing to w. Only whole secrets are
+// redacted, secrets shorter than minRedactedLength are ignored as they'd redact unrelated output.
+func newRedactingWriter(w io.Writer, secrets []string) io.Writer {
+	toRedact := []string{}
+	for _, secret := range secrets {
+		if len(strings.TrimSpace(secret)) >= minRedactedLength {
+			toRedact = append(toRedact, secret)
+		}
+	}
+	if len(toRedact) == 0 {
+		return w
+	}
+
+	// Longest first so a secret containing another is fully redacted
+	sort.Slice(toRedact, func(i, j int) bool {
+		return len(toRedact[i]) > len(toRedact[j])
+	})
+	return &redactingWriter{w: w, secrets: toRedact}
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, redactSecrets(string(p), r.secrets)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactSecrets replaces all occurrences of the secrets in s
+func redactSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+// withSecretValues creates the App's user Secret with the secret values, if any, and returns the App with it
+// added as an extra config
+func (s *suite) withSecretValues(ctx context.Context, app *application.Application) (*application.Application, error) {
+	if s.secretValues == "" {
+		return app, nil
+	}
+
+	secretName := fmt.Sprintf("%s-user-secrets", app.InstallName)
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: app.GetNamespace(),
+		},
+		StringData: map[string]string{
+			"values": s.secretValues,
+		},
+	}
+	err := state.GetFramework().MC().CreateOrUpdate(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+	s.secretValuesSecret = secretName
+
+	extraConfigs := append([]v1alpha1.AppExtraConfig{}, app.ExtraConfigs...)
+	extraConfigs = append(extraConfigs, v1alpha1.AppExtraConfig{
+		Kind:      "secret",
+		Name:      secretName,
+		Namespace: app.GetNamespace(),
+		Priority:  secretValuesPriority,
+	})
+	return app.WithExtraConfigs(extraConfigs), nil
+}
+
+// deleteSecretValues deletes the App's user Secret created for the secret values
+func (s *suite) deleteSecretValues() {
+	app := getInstallApp()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.secretValuesSecret,
+			Namespace: app.GetNamespace(),
+		},
+	}
+	logger.Log("Deleting secret values Secret %s/%s", secret.Namespace, secret.Name)
+	err := state.GetFramework().MC().Delete(state.GetContext(), secret)
+	if err != nil && !errors.IsNotFound(err) {
+		Expect(err).NotTo(HaveOccurred())
+	}
+}
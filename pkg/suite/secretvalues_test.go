@@ -0,0 +1,130 @@
+package suite
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSetValuesPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		values       map[string]any
+		valuesPath   string
+		expected     map[string]any
+		expectsError bool
+	}{
+		{
+			name:       "top level",
+			values:     map[string]any{},
+			valuesPath: "apiToken",
+			expected:   map[string]any{"apiToken": "token"},
+		},
+		{
+			name:       "creates parent maps",
+			values:     map[string]any{},
+			valuesPath: "auth.apiToken",
+			expected:   map[string]any{"auth": map[string]any{"apiToken": "token"}},
+		},
+		{
+			name:       "keeps existing values",
+			values:     map[string]any{"auth": map[string]any{"user": "admin"}},
+			valuesPath: "auth.apiToken",
+			expected:   map[string]any{"auth": map[string]any{"user": "admin", "apiToken": "token"}},
+		},
+		{
+			name:         "parent isn't a map",
+			values:       map[string]any{"auth": "disabled"},
+			valuesPath:   "auth.apiToken",
+			expectsError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := setValuesPath(tc.values, tc.valuesPath, "token")
+			if tc.expectsError {
+				if err == nil {
+					t.Fatalf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tc.values, tc.expected) {
+				t.Fatalf("Expected %v but got %v", tc.expected, tc.values)
+			}
+		})
+	}
+}
+
+func TestRedactingWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		secrets  []string
+		input    string
+		expected string
+	}{
+		{
+			name:     "no secrets",
+			secrets:  []string{},
+			input:    "Installing App hello-world",
+			expected: "Installing App hello-world",
+		},
+		{
+			name:     "redacts secret",
+			secrets:  []string{"s3cr3t"},
+			input:    "token: s3cr3t, again s3cr3t",
+			expected: "token: [REDACTED], again [REDACTED]",
+		},
+		{
+			name:     "redacts longest secret first",
+			secrets:  []string{"s3cr3t", "s3cr3t-token"},
+			input:    "value s3cr3t-token",
+			expected: "value [REDACTED]",
+		},
+		{
+			name:     "ignores short secrets",
+			secrets:  []string{"1", "aws", "true"},
+			input:    "replicas: 1, provider: aws, enabled: true",
+			expected: "replicas: 1, provider: aws, enabled: true",
+		},
+		{
+			name:     "redacts whole multi-line secrets",
+			secrets:  []string{"-----BEGIN KEY-----\nAAAABBBB\n-----END KEY-----"},
+			input:    "key:\n-----BEGIN KEY-----\nAAAABBBB\n-----END KEY-----\n",
+			expected: "key:\n[REDACTED]\n",
+		},
+		{
+			name:     "keeps lines of multi-line secrets",
+			secrets:  []string{"{\n  \"token\": \"s3cr3t\"\n}"},
+			input:    "{\n  \"replicas\": 1\n}\n-----END CERTIFICATE-----",
+			expected: "{\n  \"replicas\": 1\n}\n-----END CERTIFICATE-----",
+		},
+		{
+			name:     "ignores empty secrets",
+			secrets:  []string{"", "  "},
+			input:    "nothing to redact",
+			expected: "nothing to redact",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w := newRedactingWriter(buf, tc.secrets)
+
+			n, err := w.Write([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if n != len(tc.input) {
+				t.Fatalf("Expected %d bytes written but got %d", len(tc.input), n)
+			}
+			if buf.String() != tc.expected {
+				t.Fatalf("Expected '%s' but got '%s'", tc.expected, buf.String())
+			}
+		})
+	}
+}
@@ -28,6 +28,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	"github.com/giantswarm/apptest-framework/v5/pkg/bundles"
 	"github.com/giantswarm/apptest-framework/v5/pkg/client"
@@ -45,9 +46,21 @@ type suite struct {
 	valuesFile       string
 	bundleValuesFile string
 	isUpgrade        bool
+	upgradePath      []string
+	isRollbackTest   bool
+	isReinstallCheck bool
+	manifestDiff     bool
 	installNamespace string
 	inCluster        bool
 
+	secretValuesFile    string
+	secretValuesFromEnv []secretValueFromEnv
+	secretValues        string
+	secretValuesSecret  string
+
+	extraConfigs       []extraConfig
+	extraConfigObjects []cr.Object
+
 	isMCTest bool
 
 	inBundleApp             string
@@ -57,6 +70,7 @@ type suite struct {
 
 	// HelmRelease mode
 	useHelmRelease           bool
+	migrateToHelmRelease     bool
 	helmSourceKind           client.SourceKind
 	helmSourceName           string
 	helmSourceNamespace      string
@@ -67,18 +81,54 @@ type suite struct {
 	helmReleaseName          string
 	helmTimeout              time.Duration
 	helmRetries              *int
+	helmRemediationStrategy  helmv2.RemediationStrategy
+	helmUpgradeRetries       *int
 	helmServiceAccountName   string
 	helmKubeConfigSecretName string
+	helmDependsOn            []helmv2.DependencyReference
+	helmValuesFrom           []client.ValuesLayer
+	helmPostRenderers        []helmv2.PostRenderer
+	helmTests                bool
+	helmSourceAuth           *client.SourceAuth
+
+	localChartDir string
+	localChart    *client.LocalChart
+
+	valuesVariants          []valuesVariant
+	valuesVariantsReinstall bool
+
+	installer             Installer
+	dependencies          []dependency
+	uninstallVerification bool
 
 	afterClusterReady func()
 	beforeUpgrade     func()
 	tests             func()
+	afterRollback     func()
+	afterReinstall    func()
 	afterSuite        func()
+
+	// Set while running
+	hasFailures    bool
+	setupCompleted bool
+	cancelContext  context.CancelFunc
 }
 
 // New create a new suite instance that allows configuring an App test suite
 func New() *suite {
 	testConfig := config.MustLoad()
+	for bundleName, bundleConfig := range testConfig.Bundles {
+		naming, err := bundles.ParseAppNameOverrideType(bundleConfig.Naming)
+		if err != nil {
+			panic(fmt.Sprintf("invalid config for bundle '%s': %v", bundleName, err))
+		}
+		bundles.Register(bundleName, bundles.Bundle{
+			NameConvention: naming,
+			ValuesKeyPath:  bundleConfig.ValuesKeyPath,
+			NamespaceField: bundleConfig.NamespaceField,
+		})
+	}
+
 	return &suite{
 		appName:                 testConfig.AppName,
 		installName:             testConfig.AppName,
@@ -93,6 +143,8 @@ func New() *suite {
 		inBundleApp:             "",
 		inBundleAppOverrideType: bundles.AppNameOverrideAuto,
 		inCluster:               false,
+		uninstallVerification:   true,
+		manifestDiff:            true,
 	}
 }
 
@@ -105,6 +157,37 @@ func (s *suite) WithIsUpgrade(isUpgrade bool) *suite {
 	return s
 }
 
+// WithUpgradePath sets the versions of the App to install, in order, before upgrading to the test version.
+// Each entry can either be an explicit version (e.g. `1.2.3`) or one of the following:
+//   - `latest` - the latest released version of the App
+//   - `latest-N` - the Nth release before the latest released version (e.g. `latest-1`)
+//   - `release-pinned` - the version of the App pinned by the cluster's Release
+//
+// The `BeforeUpgrade` tests are run after each of the versions has been installed.
+// Setting this implies `WithIsUpgrade(true)`. If not set, upgrade tests install only `latest`.
+func (s *suite) WithUpgradePath(versions []string) *suite {
+	s.upgradePath = versions
+	s.isUpgrade = len(versions) > 0
+	return s
+}
+
+// WithRollbackTest enables rolling the App back to the previously released version after
+// the `Tests` have passed. The rollback target is the last version of the upgrade path for
+// upgrade suites and the latest released version otherwise.
+// Use `AfterRollback` to provide tests to run against the rolled back App.
+func (s *suite) WithRollbackTest() *suite {
+	s.isRollbackTest = true
+	return s
+}
+
+// WithReinstallCheck enables an additional phase after the tests have run that uninstalls the App,
+// waits for it to be fully removed and then installs the same version again.
+// Use AfterReinstall to run tests against the reinstalled App.
+func (s *suite) WithReinstallCheck() *suite {
+	s.isReinstallCheck = true
+	return s
+}
+
 // WithInstallNamespace sets the namespace to install the App into.
 // If not set this defaults to the `default` namespapce.
 func (s *suite) WithInstallNamespace(namespace string) *suite {
@@ -150,7 +233,8 @@ func (s *suite) InAppBundle(appBundleName string) *suite {
 }
 
 // WithBundleOverrideType sets the naming convention for the child app in the bundle values.
-// If not set, it defaults to AppNameOverrideAuto which auto-detects based on the bundle app name.
+// If not set, it defaults to AppNameOverrideAuto which uses the convention of the bundle from the registry, or
+// `bundles` in config.yaml, falling back to detecting it from the bundle's published values.yaml.
 func (s *suite) WithBundleOverrideType(overrideType bundles.AppNameOverrideType) *suite {
 	s.inBundleAppOverrideType = overrideType
 	return s
@@ -203,6 +287,23 @@ func (s *suite) WithHelmSourceURL(url string) *suite {
 	return s
 }
 
+// WithHelmSourceAuth configures the authentication and signature verification of the source CR
+// created for the HelmRelease, e.g. to install charts from private or signed registries.
+func (s *suite) WithHelmSourceAuth(auth client.SourceAuth) *suite {
+	if auth.CAFile != "" {
+		auth.CAFile, _ = filepath.Abs(auth.CAFile)
+	}
+	verifyFiles := []string{}
+	for _, file := range auth.VerifyFiles {
+		absPath, _ := filepath.Abs(file)
+		verifyFiles = append(verifyFiles, absPath)
+	}
+	auth.VerifyFiles = verifyFiles
+
+	s.helmSourceAuth = &auth
+	return s
+}
+
 // WithHelmTargetNamespace sets the target namespace where the Helm chart will be installed.
 // This maps to the HelmRelease spec.targetNamespace field.
 // If not set, the chart is installed in the HelmRelease's own namespace.
@@ -240,6 +341,17 @@ func (s *suite) WithHelmRetries(retries int) *suite {
 	return s
 }
 
+// WithHelmRemediation sets the strategy used to remediate a failed upgrade (`helmv2.RollbackRemediationStrategy`
+// or `helmv2.UninstallRemediationStrategy`) and the number of retries for upgrade remediation. The install
+// remediation retries are still set via WithHelmRetries.
+// If not set, failed upgrades are rolled back and retried as set via WithHelmRetries.
+// Regardless of the strategy, upgrades are failed if helm-controller had to remediate the new release.
+func (s *suite) WithHelmRemediation(strategy helmv2.RemediationStrategy, retries int) *suite {
+	s.helmRemediationStrategy = strategy
+	s.helmUpgradeRetries = &retries
+	return s
+}
+
 // WithHelmServiceAccountName sets the Kubernetes service account to impersonate
 // when reconciling the HelmRelease. If not set, defaults to the appName from config.
 // The service account is auto-created if it doesn't exist.
@@ -255,6 +367,51 @@ func (s *suite) WithHelmKubeConfigSecretName(name string) *suite {
 	return s
 }
 
+// WithHelmDependsOn adds a HelmRelease that must be ready before the HelmRelease of the App is reconciled.
+// If the namespace is empty the dependency is expected in the same namespace as the App's HelmRelease.
+// HelmRelease dependencies added via WithDependency can be referenced by the name they were provided with.
+func (s *suite) WithHelmDependsOn(name, namespace string) *suite {
+	s.helmDependsOn = append(s.helmDependsOn, helmv2.DependencyReference{Name: name, Namespace: namespace})
+	return s
+}
+
+// WithHelmValuesFrom adds a ConfigMap or Secret values layer to the HelmRelease's spec.valuesFrom.
+// Layers are merged in the order they are added, before the values from WithValuesFile.
+// If the layer has Values set the ConfigMap or Secret is created in the HelmRelease namespace.
+func (s *suite) WithHelmValuesFrom(layer client.ValuesLayer) *suite {
+	s.helmValuesFrom = append(s.helmValuesFrom, layer)
+	return s
+}
+
+// WithHelmValuesFromFile adds a values layer to the HelmRelease's spec.valuesFrom using a ConfigMap
+// or Secret (set via kind) with the given name that is created from the content of the values file.
+// Panics if the file can't be read.
+func (s *suite) WithHelmValuesFromFile(kind, name, valuesFile string) *suite {
+	content, err := os.ReadFile(valuesFile) // #nosec G304
+	if err != nil {
+		panic(fmt.Sprintf("failed to read values layer file %s: %v", valuesFile, err))
+	}
+	return s.WithHelmValuesFrom(client.ValuesLayer{Kind: kind, Name: name, Values: string(content)})
+}
+
+// WithHelmPostRenderersFile loads the HelmRelease's spec.postRenderers from a YAML file containing
+// a list of post renderers in the same format as used in a HelmRelease, e.g. Kustomize patches.
+// Panics if the file can't be read or parsed.
+func (s *suite) WithHelmPostRenderersFile(postRenderersFile string) *suite {
+	content, err := os.ReadFile(postRenderersFile) // #nosec G304
+	if err != nil {
+		panic(fmt.Sprintf("failed to read post renderers file %s: %v", postRenderersFile, err))
+	}
+
+	postRenderers := []helmv2.PostRenderer{}
+	err = yaml.UnmarshalStrict(content, &postRenderers)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse post renderers file %s: %v", postRenderersFile, err))
+	}
+	s.helmPostRenderers = append(s.helmPostRenderers, postRenderers...)
+	return s
+}
+
 // AfterClusterReady allows configuring tests that will run as soon as the cluster is up and ready.
 // This allows for running tests to check the current state of the cluster and
 // assert that any pre-requisites are met.
@@ -281,6 +438,21 @@ func (s *suite) BeforeUpgrade(fn func()) *suite {
 	return s
 }
 
+// AfterRollback allows configuring tests that will run after the App has been rolled back
+// to the previously released version.
+// This only runs if `WithRollbackTest` has been called.
+func (s *suite) AfterRollback(fn func()) *suite {
+	s.afterRollback = fn
+	return s
+}
+
+// AfterReinstall allows for specifying tests to run after the App has been uninstalled and installed again.
+// Only used when WithReinstallCheck is set.
+func (s *suite) AfterReinstall(fn func()) *suite {
+	s.afterReinstall = fn
+	return s
+}
+
 // Tests allows specifying all the tests to run against the App after it has finished
 // installing (and upgrading if an upgrade test suite).
 func (s *suite) Tests(fn func()) *suite {
@@ -295,9 +467,18 @@ func (s *suite) Tests(fn func()) *suite {
 // unpredictable and is not recommended.
 func (s *suite) Run(t *testing.T, suiteName string) {
 	RegisterFailHandler(Fail)
+	state.SetSuiteName(suiteName)
+	suiteStart := time.Now()
+
+	if err := s.validateMigration(); err != nil {
+		panic(err)
+	}
+	if err := s.validateValuesVariants(); err != nil {
+		panic(err)
+	}
 
 	// Ensure we use an actual semver version instead of "latest"
-	if os.Getenv("E2E_APP_VERSION") == "latest" {
+	if os.Getenv("E2E_APP_VERSION") == "latest" && s.localChartDir == "" {
 		latestVersion, err := application.GetLatestAppVersion(s.repoName)
 		if err != nil {
 			panic(err)
@@ -313,7 +494,10 @@ func (s *suite) Run(t *testing.T, suiteName string) {
 	}
 
 	BeforeSuite(func() {
-		logger.LogWriter = GinkgoWriter
+		secretValues, secrets, err := s.loadSecretValues()
+		Expect(err).NotTo(HaveOccurred())
+		s.secretValues = secretValues
+		logger.LogWriter = newRedactingWriter(GinkgoWriter, secrets)
 
 		mcKubeconfig := os.Getenv("E2E_KUBECONFIG")
 		mcContext := os.Getenv("E2E_KUBECONFIG_CONTEXT")
@@ -322,9 +506,17 @@ func (s *suite) Run(t *testing.T, suiteName string) {
 		// Ensure all require env vars are set
 		Expect(mcKubeconfig).ToNot(BeEmpty(), "`E2E_KUBECONFIG` must be set to the kubeconfig of the test MC")
 		Expect(mcContext).ToNot(BeEmpty(), "`E2E_KUBECONFIG_CONTEXT` must be set to the context to use in the kubeconfig")
-		Expect(appVersion).ToNot(BeEmpty(), "`E2E_APP_VERSION` must be set to version of the app to test against")
+		if s.localChartDir == "" {
+			Expect(appVersion).ToNot(BeEmpty(), "`E2E_APP_VERSION` must be set to version of the app to test against")
+		}
+
+		ctx, cancel := newSuiteContext(suiteStart)
+		s.cancelContext = cancel
+		state.SetContext(ctx)
 
-		state.SetContext(context.Background())
+		if s.localChartDir != "" {
+			s.pushLocalChart(ctx, appVersion)
+		}
 
 		// Setup client for conntecting to MC
 		framework, err := clustertest.New(mcContext)
@@ -361,7 +553,7 @@ func (s *suite) Run(t *testing.T, suiteName string) {
 			WithCatalog(s.appCatalog).
 			WithOrganization(*cluster.Organization).
 			WithClusterName(cluster.Name).
-			WithVersion(appVersion).
+			WithVersion(s.getAppVersion()).
 			WithInstallNamespace(s.installNamespace).
 			MustWithValuesFile(s.valuesFile, &application.TemplateValues{}).
 			WithInCluster(s.inCluster)
@@ -426,7 +618,8 @@ func (s *suite) Run(t *testing.T, suiteName string) {
 					if replicas != 0 {
 						logger.Log("Waiting for %d control plane nodes to be ready", replicas)
 						_ = wait.For(
-							wait.AreNumNodesReady(context.Background(), wcClient, int(replicas), &cr.MatchingLabels{"node-role.kubernetes.io/control-plane": ""}),
+							wait.AreNumNodesReady(state.GetContext(), wcClient, int(replicas), &cr.MatchingLabels{"node-role.kubernetes.io/control-plane": ""}),
+							wait.WithContext(state.GetContext()),
 							wait.WithTimeout(20*time.Minute),
 							wait.WithInterval(15*time.Second),
 						)
@@ -435,7 +628,8 @@ func (s *suite) Run(t *testing.T, suiteName string) {
 				func(wcClient *clusterclient.Client) {
 					logger.Log("Waiting for worker nodes to be ready")
 					_ = wait.For(
-						wait.AreNumNodesReady(context.Background(), wcClient, 2, clusterclient.DoesNotHaveLabels{"node-role.kubernetes.io/control-plane"}),
+						wait.AreNumNodesReady(state.GetContext(), wcClient, 2, clusterclient.DoesNotHaveLabels{"node-role.kubernetes.io/control-plane"}),
+						wait.WithContext(state.GetContext()),
 						wait.WithTimeout(20*time.Minute),
 						wait.WithInterval(15*time.Second),
 					)
@@ -478,6 +672,7 @@ func (s *suite) Run(t *testing.T, suiteName string) {
 
 					if len(appNamespacedNames) > 0 {
 						Eventually(wait.IsAllAppDeployed(state.GetContext(), state.GetFramework().MC(), appNamespacedNames)).
+							WithContext(state.GetContext()).
 							WithTimeout(15 * time.Minute).
 							WithPolling(10 * time.Second).
 							Should(BeTrue())
@@ -485,6 +680,7 @@ func (s *suite) Run(t *testing.T, suiteName string) {
 
 					if len(hrNamespacedNames) > 0 {
 						Eventually(client.IsAllHelmReleasesReady(state.GetContext(), state.GetFramework().MC(), hrNamespacedNames)).
+							WithContext(state.GetContext()).
 							WithTimeout(15 * time.Minute).
 							WithPolling(10 * time.Second).
 							Should(BeTrue())
@@ -499,11 +695,32 @@ func (s *suite) Run(t *testing.T, suiteName string) {
 
 			logger.Log("Workload cluster ready to use")
 		}
+
+		s.setupCompleted = true
 	})
 
 	AfterSuite(func() {
+		// Cleanup runs with its own bounded context so it still happens after an interrupt or timeout
+		suiteCtx := state.GetContext()
+		if suiteCtx == nil {
+			suiteCtx = context.Background()
+		}
+		cleanupCtx, cancelCleanup := newCleanupContext(suiteCtx)
+		defer cancelCleanup()
+		if s.cancelContext != nil {
+			defer s.cancelContext()
+		}
+		state.SetContext(cleanupCtx)
+
+		// Failures of the BeforeSuite, e.g. the cluster standup, aren't reported to the tests' ReportAfterEach
+		if !s.setupCompleted {
+			s.hasFailures = true
+		}
+
+		keepOnFailure := s.hasFailures && isEnvTrue("E2E_KEEP_CLUSTER_ON_FAILURE") && state.GetCluster() != nil
+
 		defer func() {
-			if !s.isMCTest {
+			if !s.isMCTest && !keepOnFailure {
 				By("Deleting workload cluster", func() {
 					// We defer this to ensure it happens even if uninstalling the app fails
 					logger.Log("Deleting workload cluster")
@@ -513,10 +730,33 @@ func (s *suite) Run(t *testing.T, suiteName string) {
 			}
 		}()
 
+		if s.hasFailures {
+			By("Collecting failure diagnostics", s.collectDiagnostics)
+
+			pauseDuration := getPauseOnFailure()
+			if keepOnFailure || pauseDuration > 0 {
+				By("Writing cluster access details", s.writeClusterAccessDetails)
+			}
+			if pauseDuration > 0 {
+				By(fmt.Sprintf("Pausing for %s before cleanup", pauseDuration), func() {
+					logger.Log("`E2E_PAUSE_ON_FAILURE` is set - pausing for %s before cleaning up", pauseDuration)
+					select {
+					case <-time.After(pauseDuration):
+					case <-suiteCtx.Done():
+					}
+				})
+			}
+		}
+
 		if s.afterSuite != nil {
 			By("User-provided After Suite", s.afterSuite)
 		}
 
+		if keepOnFailure {
+			logger.Log("`E2E_KEEP_CLUSTER_ON_FAILURE` is set - keeping the App and cluster '%s' for debugging", state.GetCluster().Name)
+			return
+		}
+
 		if s.bundleValuesConfigMap != "" {
 			By("Deleting bundle values ConfigMap", func() {
 				app := getInstallApp()
@@ -533,218 +773,188 @@ func (s *suite) Run(t *testing.T, suiteName string) {
 			})
 		}
 
+		if s.secretValuesSecret != "" {
+			By("Deleting secret values Secret", s.deleteSecretValues)
+		}
+
+		if len(s.extraConfigObjects) > 0 {
+			By("Deleting extra config ConfigMaps and Secrets", s.deleteExtraConfigs)
+		}
+
+		if len(s.dependencies) > 0 {
+			defer func() {
+				// Deferred so the dependencies are still removed if uninstalling the App, or verifying it, fails
+				By("Uninstalling dependencies", s.uninstallDependencies)
+			}()
+		}
+
 		By("Uninstalling App", func() {
 			if s.isDefaultApp {
-				Skip("App is a default app - skipping")
+				logger.Log("App is a default app - skipping")
 				return
 			}
 
-			if s.useHelmRelease {
-				installName := s.getHelmReleaseName()
-				cfg := s.buildHelmReleaseConfig(installName, "")
-				logger.Log("Uninstalling HelmRelease %s/%s", cfg.Namespace, installName)
-				err := client.DeleteHelmRelease(state.GetContext(), installName, cfg.Namespace)
-				Expect(err).NotTo(HaveOccurred())
-				if cfg.SourceURL != "" {
-					err = client.DeleteHelmSource(state.GetContext(), cfg)
-					Expect(err).NotTo(HaveOccurred())
-				}
-			} else {
-				app := getInstallApp()
-				logger.Log("Uninstalling App %s (%s)", app.AppName, app.InstallName)
-				err := state.GetFramework().MC().DeleteApp(state.GetContext(), *app)
-				Expect(err).NotTo(HaveOccurred())
+			err := s.getInstaller().Uninstall(state.GetContext())
+			Expect(err).NotTo(HaveOccurred())
+
+			if s.uninstallVerification {
+				By("Verifying App has been fully uninstalled", s.verifyUninstall)
 			}
 		})
 	})
 
 	Describe("", func() {
+		ReportAfterEach(func(report SpecReport) {
+			if report.Failed() {
+				s.hasFailures = true
+			}
+		})
+
 		if s.afterClusterReady != nil {
 			Describe("After Cluster Ready", s.afterClusterReady)
 		}
 
+		if len(s.dependencies) > 0 {
+			It("Install dependencies", s.installDependencies)
+		}
+
 		It("Ensure app isn't already installed", func() {
 			if s.isDefaultApp {
 				Skip("App is a default app - skipping")
 				return
 			}
 
-			if s.useHelmRelease {
-				installName := s.getHelmReleaseName()
-				cfg := s.buildHelmReleaseConfig(installName, "")
-				logger.Log("Checking that HelmRelease %s isn't already installed", installName)
+			logger.Log("Checking that App %s isn't already installed", s.appName)
 
-				hr := &helmv2.HelmRelease{
-					ObjectMeta: v1.ObjectMeta{
-						Name:      installName,
-						Namespace: cfg.Namespace,
-					},
-				}
-				err := state.GetFramework().MC().Get(state.GetContext(), cr.ObjectKeyFromObject(hr), hr)
-				Expect(err).ToNot(BeNil())
-				Expect(errors.IsNotFound(err)).To(BeTrue())
-			} else {
-				appCR := getInstallApp()
+			exists, err := s.getInstaller().Exists(state.GetContext())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
 
-				logger.Log("Checking that App %s isn't already installed", appCR.AppName)
+		if s.isUpgrade {
+			for i, entry := range s.getUpgradePath() {
+				Describe("Install previous version of app", func() {
+					It(fmt.Sprintf("Install the '%s' release of the application", entry), func() {
+						if s.isDefaultApp && len(s.upgradePath) == 0 {
+							Skip("App is a default app - skipping")
+							return
+						}
 
-				app := &v1alpha1.App{
-					ObjectMeta: v1.ObjectMeta{
-						Name:      appCR.InstallName,
-						Namespace: appCR.GetNamespace(),
-					},
+						version := s.resolveUpgradeVersion(entry)
+						logger.Log("Upgrade path step %d: installing version '%s'", i+1, version)
+						s.installVersion(version, i > 0)
+					})
+				})
+
+				if s.beforeUpgrade != nil {
+					Describe("Before upgrade", s.beforeUpgrade)
 				}
-				err := state.GetFramework().MC().Get(state.GetContext(), cr.ObjectKeyFromObject(app), app)
-				Expect(err).ToNot(BeNil())
-				Expect(errors.IsNotFound(err)).To(BeTrue())
 			}
-		})
-
-		if s.isUpgrade {
-			Describe("Install previous version of app", func() {
-				It("Install the latest release of the application", func() {
-					if s.isDefaultApp {
-						Skip("App is a default app - skipping")
-						return
-					}
+		}
 
-					if s.useHelmRelease {
-						latestVersion, err := application.GetLatestAppVersion(s.repoName)
-						Expect(err).NotTo(HaveOccurred())
-						latestVersion = strings.TrimPrefix(latestVersion, "v")
+		if s.isUpgrade && s.manifestDiff {
+			Describe("Manifest diff", func() {
+				It("Diff the rendered manifests of the installed and tested version", s.diffManifests)
+			})
+		}
 
-						installName := s.getHelmReleaseName()
+		Describe("Install app", func() {
+			It("Install the application with the version to test", func() {
+				if s.isDefaultApp && !s.isUpgrade && !s.useHelmRelease {
+					Skip("App is a default app - skipping")
+					return
+				}
 
-						ctx, cancel := context.WithTimeout(state.GetContext(), s.getHelmInstallTimeout())
-						defer cancel()
+				// Default apps are upgraded via a release upgrade, everything else via the Installer
+				s.installVersion(s.getAppVersion(), s.isUpgrade)
+			})
+		})
 
-						cfg := s.buildHelmReleaseConfig(installName, latestVersion)
-						client.InstallHelmRelease(ctx, cfg)
-					} else {
-						var app *application.Application
-						if s.inBundleApp != "" {
-							cluster := state.GetCluster()
-							app = application.New(fmt.Sprintf("%s-%s", cluster.Name, s.inBundleApp), s.inBundleApp).
-								WithCatalog(s.appCatalog).
-								WithOrganization(*cluster.Organization).
-								WithClusterName(cluster.Name).
-								WithVersion("latest").
-								WithInstallNamespace(cluster.Organization.GetNamespace()).
-								MustWithValues(fmt.Sprintf("clusterID: %s", cluster.Name), &application.TemplateValues{}).
-								WithInCluster(true)
-						} else {
-							app = state.GetApplication().WithVersion("latest")
-						}
+		if s.migrateToHelmRelease {
+			Describe("Migrate app to HelmRelease", func() {
+				It("Migrate the App CR to a HelmRelease adopting the installed Helm release", s.migrateAppToHelmRelease)
+			})
+		}
 
-						ctx, cancel := context.WithTimeout(state.GetContext(), 5*time.Minute)
-						defer cancel()
-						client.InstallApp(ctx, app)
+		if s.helmTests {
+			Describe("Helm tests", func() {
+				It("Run the Helm tests of the chart", func() {
+					if s.hasFailures {
+						Skip("Previous tests have failed - skipping Helm tests")
+						return
 					}
+					s.runHelmTests()
 				})
 			})
+		}
 
-			if s.beforeUpgrade != nil {
-				Describe("Before upgrade", s.beforeUpgrade)
+		if s.tests != nil {
+			if len(s.valuesVariants) > 0 {
+				s.runValuesVariants()
+			} else {
+				Describe("App Tests", s.tests)
 			}
 		}
 
-		Describe("Install app", func() {
-			It("Install the application with the version to test", func() {
-				if s.useHelmRelease {
-					appVersion := os.Getenv("E2E_APP_VERSION")
-					Expect(appVersion).NotTo(BeEmpty(), "E2E_APP_VERSION must be set for HelmRelease tests")
-					installName := s.getHelmReleaseName()
+		if s.isRollbackTest {
+			Describe("Rollback app", func() {
+				It("Rollback the application to the previously released version", func() {
+					if s.hasFailures {
+						Skip("Previous tests have failed - skipping rollback")
+						return
+					}
 
-					ctx, cancel := context.WithTimeout(state.GetContext(), s.getHelmInstallTimeout())
-					defer cancel()
+					path := s.getUpgradePath()
+					version := s.resolveUpgradeVersion(path[len(path)-1])
+					logger.Log("Rolling back to version '%s'", version)
+					s.installVersion(version, true)
+				})
+			})
 
-					cfg := s.buildHelmReleaseConfig(installName, appVersion)
+			if s.afterRollback != nil {
+				Describe("After rollback", s.afterRollback)
+			}
+		}
 
-					if s.isUpgrade {
-						// Upgrade: update the existing HelmRelease version
-						client.UpdateHelmReleaseVersion(ctx, cfg, appVersion)
-					} else {
-						client.InstallHelmRelease(ctx, cfg)
+		if s.isReinstallCheck {
+			Describe("Reinstall app", func() {
+				It("Uninstall the application", func() {
+					if s.isDefaultApp {
+						Skip("App is a default app - skipping")
+						return
 					}
-
-					// Wait for the HelmRelease to be ready at the expected version
-					Eventually(func() (bool, error) {
-						ready, err := client.IsHelmReleaseReady(state.GetContext(), installName, cfg.Namespace)
-						if !ready || err != nil {
-							return false, err
-						}
-						return client.IsHelmReleaseVersion(state.GetContext(), installName, cfg.Namespace, appVersion)
-					}).
-						WithContext(ctx).
-						WithPolling(5 * time.Second).
-						Should(BeTrue())
-				} else if s.isDefaultApp && s.isUpgrade {
-					// If we're testing the upgrade of a default app we need to do so via a release upgrade
-					cluster := state.GetCluster()
-					app := state.GetApplication()
-					bundleApp := state.GetBundleApplication()
-					if bundleApp != nil {
-						cluster = cluster.WithAppOverride(*bundleApp)
-					} else {
-						cluster = cluster.WithAppOverride(*app)
+					if s.hasFailures {
+						Skip("Previous tests have failed - skipping reinstall")
+						return
 					}
 
-					ctx, cancel := context.WithTimeout(state.GetContext(), 10*time.Minute)
-					defer cancel()
-					_, err := state.GetFramework().ApplyCluster(ctx, cluster)
-					Expect(err).ToNot(HaveOccurred())
+					err := s.getInstaller().Uninstall(state.GetContext())
+					Expect(err).NotTo(HaveOccurred())
 
-				} else if s.isDefaultApp {
-					Skip("App is a default app - skipping")
-					return
-				} else {
-					app := getInstallApp()
-
-					ctx, cancel := context.WithTimeout(state.GetContext(), 5*time.Minute)
-					defer cancel()
-
-					if state.GetBundleApplication() != nil {
-						if _, err := os.Stat(s.bundleValuesFile); err == nil {
-							bundleValuesContent, err := os.ReadFile(s.bundleValuesFile)
-							Expect(err).NotTo(HaveOccurred())
-
-							configMapName := fmt.Sprintf("%s-bundle-values", app.InstallName)
-							configMap := &corev1.ConfigMap{
-								TypeMeta: v1.TypeMeta{
-									Kind:       "ConfigMap",
-									APIVersion: "v1",
-								},
-								ObjectMeta: v1.ObjectMeta{
-									Name:      configMapName,
-									Namespace: app.GetNamespace(),
-								},
-								Data: map[string]string{
-									"values": string(bundleValuesContent),
-								},
-							}
-							err = state.GetFramework().MC().CreateOrUpdate(ctx, configMap)
-							Expect(err).NotTo(HaveOccurred())
-							s.bundleValuesConfigMap = configMapName
-
-							app = app.WithExtraConfigs([]v1alpha1.AppExtraConfig{
-								{
-									Kind:      "configMap",
-									Name:      configMapName,
-									Namespace: app.GetNamespace(),
-									Priority:  25,
-								},
-							})
-						}
+					if s.uninstallVerification {
+						s.verifyUninstall()
+					} else {
+						s.waitForRemoval()
 					}
+				})
 
-					client.InstallApp(ctx, app)
-				}
+				It("Install the application with the version to test again", func() {
+					if s.isDefaultApp {
+						Skip("App is a default app - skipping")
+						return
+					}
+					if s.hasFailures {
+						Skip("Previous tests have failed - skipping reinstall")
+						return
+					}
 
+					s.installVersion(s.getAppVersion(), false)
+				})
 			})
-		})
 
-		if s.tests != nil {
-			Describe("App Tests", s.tests)
+			if s.afterReinstall != nil {
+				Describe("After reinstall", s.afterReinstall)
+			}
 		}
 	})
 
@@ -760,6 +970,122 @@ func getInstallApp() *application.Application {
 	return state.GetApplication()
 }
 
+// getUpgradePath returns the versions to install before upgrading to the test version.
+// Defaults to only the latest released version if not explicitly set via WithUpgradePath.
+func (s *suite) getUpgradePath() []string {
+	if len(s.upgradePath) > 0 {
+		return s.upgradePath
+	}
+	return []string{UpgradePathLatest}
+}
+
+// installVersion installs the given version of the App, or upgrades / downgrades the existing
+// install to it if isInstalled is set, and waits for it to be ready at that version.
+func (s *suite) installVersion(version string, isInstalled bool) {
+	GinkgoHelper()
+
+	if s.isDefaultApp && !s.useHelmRelease && s.installer == nil {
+		s.installDefaultAppVersion(version)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(state.GetContext(), s.getInstallTimeout())
+	defer cancel()
+
+	installer := s.getInstaller()
+	if isInstalled {
+		Expect(installer.Upgrade(ctx, version)).To(Succeed())
+	} else {
+		Expect(installer.Install(ctx, version)).To(Succeed())
+	}
+
+	if s.installer != nil {
+		// The built-in installers wait for the version themselves, custom ones are checked to have done the same
+		verifyInstalledVersion(ctx, installer, version)
+	}
+}
+
+// verifyInstalledVersion checks the App installed by the Installer is ready at the given version
+func verifyInstalledVersion(ctx context.Context, installer Installer, version string) {
+	GinkgoHelper()
+
+	ready, err := installer.IsReady(ctx)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(ready).To(BeTrue(), "the Installer returned before the App was ready")
+
+	currentVersion, err := installer.CurrentVersion(ctx)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(strings.TrimPrefix(currentVersion, "v")).To(Equal(strings.TrimPrefix(version, "v")), "the Installer returned before the App was at the expected version")
+}
+
+// installDefaultAppVersion upgrades (or downgrades) a default App to the given version via the
+// cluster's App overrides and waits for it to be deployed at that version.
+func (s *suite) installDefaultAppVersion(version string) {
+	GinkgoHelper()
+
+	ctx, cancel := context.WithTimeout(state.GetContext(), 10*time.Minute)
+	defer cancel()
+
+	app := s.getInstallAppWithVersion(version)
+	applyDefaultAppOverride(ctx, app)
+
+	builtApp, _, err := app.Build()
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(wait.IsAppVersion(state.GetContext(), state.GetFramework().MC(), app.InstallName, app.GetNamespace(), builtApp.Spec.Version)).
+		WithContext(ctx).
+		WithPolling(5 * time.Second).
+		Should(BeTrue())
+	Eventually(wait.IsAppDeployed(state.GetContext(), state.GetFramework().MC(), app.InstallName, app.GetNamespace())).
+		WithContext(ctx).
+		WithPolling(5 * time.Second).
+		Should(BeTrue())
+}
+
+// getInstallAppWithVersion returns a copy of the App to install with the App being tested set to the
+// provided version. When testing within a bundle the bundle App is returned with the child App
+// version overridden. The Apps held in the state are left unchanged.
+func (s *suite) getInstallAppWithVersion(version string) *application.Application {
+	GinkgoHelper()
+
+	app := *state.GetApplication()
+	app.WithVersion(version)
+
+	bundleApp := state.GetBundleApplication()
+	if bundleApp == nil {
+		return &app
+	}
+
+	bundleAppCopy := *bundleApp
+	installApp, err := bundles.OverrideChildApp(&bundleAppCopy, &app, s.inBundleAppOverrideType)
+	Expect(err).NotTo(HaveOccurred())
+	return installApp
+}
+
+// applyDefaultAppOverride upgrades a default App by applying the cluster with the given App override
+func applyDefaultAppOverride(ctx context.Context, app *application.Application) {
+	GinkgoHelper()
+
+	cluster := state.GetCluster().WithAppOverride(*app)
+	_, err := state.GetFramework().ApplyCluster(ctx, cluster)
+	Expect(err).ToNot(HaveOccurred())
+}
+
+// waitForHelmReleaseVersion waits for the HelmRelease to be ready at the expected version
+func waitForHelmReleaseVersion(ctx context.Context, cfg client.HelmReleaseConfig, version string) {
+	GinkgoHelper()
+
+	Eventually(func() (bool, error) {
+		ready, err := client.IsHelmReleaseReady(state.GetContext(), cfg.Name, cfg.Namespace)
+		if !ready || err != nil {
+			return false, err
+		}
+		return client.IsHelmReleaseVersion(state.GetContext(), cfg.Name, cfg.Namespace, version)
+	}).
+		WithContext(ctx).
+		WithPolling(5 * time.Second).
+		Should(BeTrue())
+}
+
 // resolveBundleVersion determines the version and catalog of the bundle App to install.
 //
 // By default the bundle is pinned to the version shipped by the cluster's Release, so suites
@@ -882,6 +1208,28 @@ func (s *suite) getHelmInstallTimeout() time.Duration {
 	return 10 * time.Minute
 }
 
+// getInstallTimeout returns the timeout to use for install/upgrade operations of the Installer.
+// App CR installs default to 5 minutes, everything else uses the HelmRelease timeout.
+func (s *suite) getInstallTimeout() time.Duration {
+	if s.installer == nil && !s.useHelmRelease {
+		return 5 * time.Minute
+	}
+	return s.getHelmInstallTimeout()
+}
+
+// getAppVersion returns the version of the App to test, the version the local chart was pushed with if set,
+// otherwise from `E2E_APP_VERSION`.
+func (s *suite) getAppVersion() string {
+	GinkgoHelper()
+
+	if s.localChart != nil {
+		return s.localChart.Version
+	}
+	appVersion := os.Getenv("E2E_APP_VERSION")
+	Expect(appVersion).NotTo(BeEmpty(), "E2E_APP_VERSION must be set")
+	return appVersion
+}
+
 // loadValues reads the values file and returns its content as a string.
 // Returns an empty string if the file does not exist.
 func (s *suite) loadValues() string {
@@ -934,7 +1282,12 @@ func (s *suite) buildHelmReleaseConfig(installName, chartVersion string) client.
 		}
 	}
 
-	return client.HelmReleaseConfig{
+	// Extra configs are layered around the values file by priority, as app-operator does
+	extraConfigsBefore, extraConfigsAfter := s.helmExtraConfigLayers(installName)
+	valuesFrom := append([]client.ValuesLayer{}, s.helmValuesFrom...)
+	valuesFrom = append(valuesFrom, extraConfigsBefore...)
+
+	return s.withLocalChartSource(client.HelmReleaseConfig{
 		Name:                 installName,
 		Namespace:            namespace,
 		TargetNamespace:      s.helmTargetNamespace,
@@ -946,10 +1299,19 @@ func (s *suite) buildHelmReleaseConfig(installName, chartVersion string) client.
 		SourceName:           s.helmSourceName,
 		SourceNamespace:      sourceNamespace,
 		SourceURL:            s.helmSourceURL,
+		SourceAuth:           s.helmSourceAuth,
 		Timeout:              s.helmTimeout,
 		Retries:              s.helmRetries,
+		RemediationStrategy:  s.helmRemediationStrategy,
+		UpgradeRetries:       s.helmUpgradeRetries,
 		ServiceAccountName:   serviceAccountName,
 		KubeConfigSecretName: kubeConfigSecret,
 		Values:               s.loadValues(),
-	}
+		SecretValues:         s.secretValues,
+		DependsOn:            s.getHelmDependsOn(),
+		ValuesFrom:           valuesFrom,
+		ValuesOverrides:      extraConfigsAfter,
+		PostRenderers:        s.helmPostRenderers,
+		EnableTests:          s.helmTests,
+	})
 }
@@ -0,0 +1,113 @@
+package suite
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+	. "github.com/onsi/gomega"    //nolint:staticcheck
+)
+
+// verifyUninstall waits for the installed CR to be fully removed and then ensures that the Helm release
+// and all the objects it managed have been removed from the cluster the App was installed into.
+func (s *suite) verifyUninstall() {
+	GinkgoHelper()
+
+	ctx := state.GetContext()
+
+	s.waitForRemoval()
+
+	if s.installer != nil {
+		logger.Log("Custom installer in use - skipping check for leftover resources")
+		return
+	}
+
+	releaseName, releaseNamespace, storageNamespace := s.getHelmReleaseLocation()
+
+	var clusterClient cr.Client = state.GetFramework().MC()
+	if !s.isMCTest {
+		wcClient, err := state.GetFramework().WC(state.GetCluster().Name)
+		Expect(err).NotTo(HaveOccurred())
+		clusterClient = wcClient
+	}
+
+	logger.Log("Checking for resources left behind by Helm release %s/%s", releaseNamespace, releaseName)
+	var leftovers []string
+	Eventually(func() ([]string, error) {
+		var err error
+		leftovers, err = client.FindHelmReleaseLeftovers(ctx, clusterClient, releaseName, releaseNamespace, storageNamespace)
+		return leftovers, err
+	}).
+		WithContext(state.GetContext()).
+		WithTimeout(5*time.Minute).
+		WithPolling(10*time.Second).
+		Should(BeEmpty(), func() string {
+			return fmt.Sprintf("resources of Helm release %s/%s were left behind after uninstalling:\n  %s",
+				releaseNamespace, releaseName, strings.Join(leftovers, "\n  "))
+		})
+}
+
+// waitForRemoval waits for the installed CR to be fully removed, including any finalizers.
+func (s *suite) waitForRemoval() {
+	GinkgoHelper()
+
+	logger.Log("Waiting for the App to be fully removed")
+	Eventually(func() (bool, error) {
+		return s.getInstaller().Exists(state.GetContext())
+	}).
+		WithContext(state.GetContext()).
+		WithTimeout(5*time.Minute).
+		WithPolling(5*time.Second).
+		Should(BeFalse(), "the App was not removed after being uninstalled")
+}
+
+// getHelmReleaseLocation returns the name of the Helm release backing the installed App along with
+// the namespace it was installed into and the namespace its release storage Secrets live in.
+func (s *suite) getHelmReleaseLocation() (releaseName, releaseNamespace, storageNamespace string) {
+	if !s.useHelmRelease {
+		// chart-operator names the release after the Chart CR, which is the App name without the cluster prefix
+		app := state.GetApplication()
+		releaseName = app.InstallName
+		if cluster := state.GetCluster(); cluster != nil {
+			releaseName = strings.TrimPrefix(releaseName, fmt.Sprintf("%s-", cluster.Name))
+		}
+		return releaseName, app.InstallNamespace, app.InstallNamespace
+	}
+
+	cfg := s.buildHelmReleaseConfig(s.getHelmReleaseName(), "")
+
+	releaseNamespace = cfg.Namespace
+	if cfg.TargetNamespace != "" {
+		releaseNamespace = cfg.TargetNamespace
+	}
+
+	// helm-controller defaults the release name to `[{targetNamespace}-]{name}`
+	releaseName = cfg.ReleaseName
+	if releaseName == "" {
+		releaseName = cfg.Name
+		if cfg.TargetNamespace != "" {
+			releaseName = fmt.Sprintf("%s-%s", cfg.TargetNamespace, cfg.Name)
+		}
+	}
+
+	storageNamespace = cfg.StorageNamespace
+	if storageNamespace == "" {
+		storageNamespace = cfg.Namespace
+	}
+
+	return releaseName, releaseNamespace, storageNamespace
+}
+
+// WithUninstallVerification sets if the suite should verify that uninstalling the App removed the
+// Helm release and all the resources it created. Defaults to true.
+func (s *suite) WithUninstallVerification(enabled bool) *suite {
+	s.uninstallVerification = enabled
+	return s
+}
@@ -0,0 +1,137 @@
+package suite
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/giantswarm/clustertest/v5/pkg/application"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/client"
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	"github.com/onsi/ginkgo/v2/types"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+	. "github.com/onsi/gomega"    //nolint:staticcheck
+)
+
+// valuesVariant is a named values file the App is installed with before running the tests
+type valuesVariant struct {
+	name       string
+	valuesFile string
+}
+
+// WithValuesVariants sets multiple values files, keyed by a variant name, that the App is tested with.
+// The `Tests` are run once per variant, in alphabetical order of the variant names, with the App
+// updated in place to use the variant's values before each run. Apps installed via a custom Installer
+// are reinstalled instead. The specs of each variant are labelled with the variant name so variants can be
+// excluded with `--label-filter`, e.g. `--label-filter='!default'`. Selecting a single variant also filters out
+// the unlabelled install steps, so isn't supported.
+// The variant names must be valid Ginkgo labels. Not supported with `InAppBundle` or default Apps.
+// This replaces any values file set with WithValuesFile.
+func (s *suite) WithValuesVariants(variants map[string]string) *suite {
+	s.valuesVariants = []valuesVariant{}
+	for name, valuesFile := range variants {
+		if _, err := types.ValidateAndCleanupLabel(name, types.CodeLocation{}); err != nil {
+			panic(fmt.Sprintf("invalid values variant name '%s', it must be a valid Ginkgo label: %v", name, err))
+		}
+		absPath, _ := filepath.Abs(valuesFile)
+		s.valuesVariants = append(s.valuesVariants, valuesVariant{name: name, valuesFile: absPath})
+	}
+	sort.Slice(s.valuesVariants, func(i, j int) bool {
+		return s.valuesVariants[i].name < s.valuesVariants[j].name
+	})
+
+	if len(s.valuesVariants) > 0 {
+		s.valuesFile = s.valuesVariants[0].valuesFile
+	}
+	return s
+}
+
+// WithValuesVariantsReinstall sets the App to be uninstalled and installed again when switching between
+// values variants instead of being updated in place. Useful when the variants change immutable fields.
+func (s *suite) WithValuesVariantsReinstall() *suite {
+	s.valuesVariantsReinstall = true
+	return s
+}
+
+// runValuesVariants registers the `Tests` once per values variant, each preceded by switching the
+// installed App over to the variant's values.
+func (s *suite) runValuesVariants() {
+	for i, variant := range s.valuesVariants {
+		Describe(fmt.Sprintf("Values variant '%s'", variant.name), Label(variant.name), func() {
+			It(fmt.Sprintf("Install the application with the '%s' values", variant.name), func() {
+				if i == 0 {
+					// The first variant is used for the initial install
+					logger.Log("Application installed with the '%s' values", variant.name)
+					state.SetValuesVariant(variant.name)
+					return
+				}
+				s.installValuesVariant(variant)
+			})
+
+			Describe("App Tests", s.tests)
+		})
+	}
+}
+
+// installValuesVariant updates the installed App to use the values of the given variant
+func (s *suite) installValuesVariant(variant valuesVariant) {
+	GinkgoHelper()
+
+	// The values of default Apps are set via the cluster, so the new values can't be applied and waited for
+	Expect(s.isDefaultApp && !s.useHelmRelease).To(BeFalse(), "WithValuesVariants can't be used with default Apps")
+
+	logger.Log("Switching application to the '%s' values from %s", variant.name, variant.valuesFile)
+	s.valuesFile = variant.valuesFile
+
+	app := *state.GetApplication()
+	app.MustWithValuesFile(variant.valuesFile, &application.TemplateValues{})
+	state.SetApplication(&app)
+
+	appVersion := s.getAppVersion()
+
+	// Custom Installers can only upgrade, which doesn't wait for the values of the same version to be applied
+	if s.valuesVariantsReinstall || s.installer != nil {
+		err := s.getInstaller().Uninstall(state.GetContext())
+		Expect(err).NotTo(HaveOccurred())
+		s.waitForRemoval()
+		s.installVersion(appVersion, false)
+	} else {
+		s.updateValues(appVersion)
+	}
+
+	state.SetValuesVariant(variant.name)
+}
+
+// updateValues applies the current values file to the installed App, waiting until they've been released.
+// The version is unchanged so upgrading isn't enough to know the new values have been applied.
+func (s *suite) updateValues(appVersion string) {
+	GinkgoHelper()
+
+	ctx, cancel := context.WithTimeout(state.GetContext(), s.getInstallTimeout())
+	defer cancel()
+
+	if s.useHelmRelease {
+		cfg := s.buildHelmReleaseConfig(s.getHelmReleaseName(), appVersion)
+		client.UpdateHelmReleaseValues(ctx, cfg.Name, cfg.Namespace, s.loadValues())
+		return
+	}
+
+	app, err := (&appInstaller{s: s}).build(ctx, appVersion)
+	Expect(err).NotTo(HaveOccurred())
+	client.UpdateAppValues(ctx, app, app.Values, &application.TemplateValues{})
+}
+
+// validateValuesVariants returns an error if the suite is configured with values variants along with an
+// option they don't support
+func (s *suite) validateValuesVariants() error {
+	if len(s.valuesVariants) > 0 && s.inBundleApp != "" {
+		// The values would need to be applied to the child App via the bundle values
+		return fmt.Errorf("WithValuesVariants can't be used with InAppBundle")
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package suite
+
+import (
+	"testing"
+)
+
+func TestWithValuesVariantsNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		variants      map[string]string
+		expectedPanic bool
+	}{
+		{
+			name:     "valid names",
+			variants: map[string]string{"default": "values.yaml", "ha-mode": "ha.yaml", "feature:enabled": "feature.yaml"},
+		},
+		{
+			name:          "empty name",
+			variants:      map[string]string{" ": "values.yaml"},
+			expectedPanic: true,
+		},
+		{
+			name:          "invalid characters",
+			variants:      map[string]string{"ha/mode": "ha.yaml"},
+			expectedPanic: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tc.expectedPanic {
+					t.Fatalf("Expected panic: %t but got %v", tc.expectedPanic, r)
+				}
+			}()
+			(&suite{}).WithValuesVariants(tc.variants)
+		})
+	}
+}
+
+func TestValidateValuesVariants(t *testing.T) {
+	variants := []valuesVariant{{name: "default", valuesFile: "values.yaml"}}
+	tests := []struct {
+		name        string
+		suite       *suite
+		expectedErr bool
+	}{
+		{
+			name:  "no variants in bundle",
+			suite: &suite{inBundleApp: "security-bundle"},
+		},
+		{
+			name:  "variants",
+			suite: &suite{valuesVariants: variants},
+		},
+		{
+			name:        "variants in bundle",
+			suite:       &suite{valuesVariants: variants, inBundleApp: "security-bundle"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.suite.validateValuesVariants()
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("Expected error: %t but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package suite
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/giantswarm/clustertest/v5/pkg/application"
+	"github.com/giantswarm/clustertest/v5/pkg/logger"
+
+	"github.com/giantswarm/apptest-framework/v5/pkg/state"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:staticcheck
+	. "github.com/onsi/gomega"    //nolint:staticcheck
+)
+
+const (
+	// UpgradePathLatest resolves to the latest released version of the App.
+	UpgradePathLatest = "latest"
+	// UpgradePathLatestPrefix can be suffixed with a number to resolve to a release before the
+	// latest released version of the App, e.g. `latest-1` is the release before the latest.
+	UpgradePathLatestPrefix = "latest-"
+	// UpgradePathReleasePinned resolves to the version of the App pinned by the cluster's Release.
+	UpgradePathReleasePinned = "release-pinned"
+)
+
+// githubReleasesURL is the GitHub API endpoint used to list the published releases of an App.
+var githubReleasesURL = "https://api.github.com/repos/giantswarm/%s/releases"
+
+// resolveUpgradeVersion turns an entry of the upgrade path into a concrete version.
+// Entries that aren't one of the symbolic versions are returned as-is (without a `v` prefix).
+func (s *suite) resolveUpgradeVersion(entry string) string {
+	GinkgoHelper()
+
+	switch {
+	case entry == UpgradePathLatest:
+		latest, err := application.GetLatestAppVersion(s.repoName)
+		Expect(err).NotTo(HaveOccurred())
+		return strings.TrimPrefix(latest, "v")
+
+	case strings.HasPrefix(entry, UpgradePathLatestPrefix):
+		offset, err := strconv.Atoi(strings.TrimPrefix(entry, UpgradePathLatestPrefix))
+		Expect(err).NotTo(HaveOccurred(), "upgrade path entry '%s' must be in the format `latest-N`", entry)
+
+		versions, err := listReleasedVersions(s.repoName)
+		Expect(err).NotTo(HaveOccurred())
+
+		version, err := previousVersion(versions, offset)
+		Expect(err).NotTo(HaveOccurred())
+		return version
+
+	case entry == UpgradePathReleasePinned:
+		release, err := state.GetCluster().GetRelease()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(release).NotTo(BeNil(), "no Release found for cluster to resolve `%s`", entry)
+
+		for _, releaseApp := range release.Spec.Apps {
+			if releaseApp.Name == s.appName {
+				return strings.TrimPrefix(releaseApp.Version, "v")
+			}
+		}
+		Fail(fmt.Sprintf("App '%s' is not part of the cluster's Release so `%s` can't be resolved", s.appName, entry))
+	}
+
+	return strings.TrimPrefix(entry, "v")
+}
+
+// listReleasedVersions returns the tag names of all published (non-draft) GitHub releases of the given repo.
+// If `GITHUB_TOKEN` is set it is used to authenticate the requests to avoid rate limiting.
+func listReleasedVersions(repoName string) ([]string, error) {
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+
+	versions := []string{}
+	for page := 1; ; page++ {
+		url := fmt.Sprintf(githubReleasesURL+"?per_page=100&page=%d", repoName, page)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("listing releases of %s: %w", repoName, err)
+		}
+
+		releases := []struct {
+			TagName string `json:"tag_name"`
+			Draft   bool   `json:"draft"`
+		}{}
+		err = json.NewDecoder(resp.Body).Decode(&releases)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("listing releases of %s: unexpected status %s", repoName, resp.Status)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding releases of %s: %w", repoName, err)
+		}
+
+		if len(releases) == 0 {
+			break
+		}
+		for _, release := range releases {
+			if !release.Draft {
+				versions = append(versions, release.TagName)
+			}
+		}
+	}
+
+	logger.Log("Found %d releases of '%s'", len(versions), repoName)
+	return versions, nil
+}
+
+// previousVersion returns the version `offset` releases before the latest stable version found in versions.
+// Pre-release and non-semver versions are ignored.
+func previousVersion(versions []string, offset int) (string, error) {
+	if offset < 0 {
+		return "", fmt.Errorf("offset must not be negative, got %d", offset)
+	}
+
+	seen := map[string]bool{}
+	stable := semver.Collection{}
+	for _, v := range versions {
+		parsed, err := semver.NewVersion(v)
+		if err != nil || parsed.Prerelease() != "" || seen[parsed.String()] {
+			continue
+		}
+		seen[parsed.String()] = true
+		stable = append(stable, parsed)
+	}
+	sort.Sort(sort.Reverse(stable))
+
+	if offset >= len(stable) {
+		return "", fmt.Errorf("requested the release %d before the latest but only %d releases were found", offset, len(stable))
+	}
+	return stable[offset].String(), nil
+}
@@ -0,0 +1,73 @@
+package suite
+
+import (
+	"testing"
+)
+
+func TestPreviousVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		versions     []string
+		offset       int
+		expected     string
+		expectsError bool
+	}{
+		{
+			name:     "latest",
+			versions: []string{"v1.0.0", "v1.2.0", "v1.1.0"},
+			offset:   0,
+			expected: "1.2.0",
+		},
+		{
+			name:     "latest-1",
+			versions: []string{"v1.0.0", "v1.2.0", "v1.1.0"},
+			offset:   1,
+			expected: "1.1.0",
+		},
+		{
+			name:     "ignores pre-releases and invalid versions",
+			versions: []string{"v2.0.0-rc.1", "v1.2.0", "not-a-version", "v1.1.0"},
+			offset:   1,
+			expected: "1.1.0",
+		},
+		{
+			name:     "ignores duplicate versions",
+			versions: []string{"v1.2.0", "1.2.0", "v1.1.0"},
+			offset:   1,
+			expected: "1.1.0",
+		},
+		{
+			name:         "not enough releases",
+			versions:     []string{"v1.0.0"},
+			offset:       1,
+			expectsError: true,
+		},
+		{
+			name:         "negative offset",
+			versions:     []string{"v1.0.0"},
+			offset:       -1,
+			expectsError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := previousVersion(tc.versions, tc.offset)
+
+			if err != nil && !tc.expectsError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expectsError {
+				if err == nil {
+					t.Fatalf("expected an error but got version '%s'", result)
+				}
+				return
+			}
+
+			if result != tc.expected {
+				t.Fatalf("Version didn't match expected. Expected '%s', Actual: '%s'", tc.expected, result)
+			}
+		})
+	}
+}